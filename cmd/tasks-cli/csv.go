@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+	"time"
+
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/taskcsv"
+)
+
+func exportCSV(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("export-csv", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	out := flags.String("out", "-", "output file, - for standard output")
+	tz := flags.String("tz", "UTC", "time zone dates are written in")
+	search := flags.String("search", "", "only tasks whose header or text contains this")
+	status := flags.String("status", "", "only tasks in this progress status")
+	urgent := flags.String("urgent", "", "only urgent (true) or not urgent (false) tasks")
+	important := flags.String("important", "", "only important (true) or not important (false) tasks")
+	overdue := flags.String("overdue", "", "only overdue (true) or not overdue (false) tasks")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+
+	var statusPtr *string
+	if *status != "" {
+		statusPtr = status
+	}
+
+	isUrgent, err := optionalBool(*urgent)
+	if err != nil {
+		return err
+	}
+	isImportant, err := optionalBool(*important)
+	if err != nil {
+		return err
+	}
+	isOverdue, err := optionalBool(*overdue)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := r.TasksContext(
+		ctx,
+		int32(*ownerId),
+		search,
+		time.Time{},
+		time.Time{},
+		time.Time{},
+		time.Time{},
+		statusPtr,
+		isUrgent,
+		isImportant,
+		nil,
+		nil,
+		isOverdue,
+	)
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	err = taskcsv.Write(w, tasks, loc)
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func importCSV(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("import-csv", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id the tasks are imported for")
+	in := flags.String("in", "-", "input file, - for standard input")
+	mappingStr := flags.String("map", "", "column mapping like header=Title,deadline=Due")
+	tz := flags.String("tz", "UTC", "time zone of dates without one")
+	conflictStr := flags.String("conflict", string(model.ImportConflictSkip), "what to do with existing ids: skip, overwrite or duplicate")
+	dryRun := flags.Bool("dry-run", false, "validate without writing")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	mapping, err := taskcsv.ParseMapping(*mappingStr)
+	if err != nil {
+		return err
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+
+	conflict, err := model.ImportConflictFromString(*conflictStr)
+	if err != nil {
+		return err
+	}
+
+	f, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	tasks, rowErrors, err := taskcsv.Read(f, mapping, loc)
+	if err != nil {
+		return err
+	}
+
+	// rows that cannot be read fail the import, but the rest is still
+	// validated so that every problem is reported at once
+	report, err := r.ImportTasksContext(ctx, int32(*ownerId), tasks, conflict, *dryRun || len(rowErrors) != 0)
+	if err != nil {
+		return err
+	}
+	report.Errors = append(rowErrors, report.Errors...)
+	report.DryRun = *dryRun
+
+	return printReport(report)
+}
+
+// optionalBool parses a boolean flag that may be left empty.
+func optionalBool(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
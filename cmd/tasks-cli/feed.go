@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// Feed tokens are only managed from here: the shared protos module has no
+// RPCs for them yet.
+func createFeedToken(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("feed-create", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	scopeStr := flags.String("scope", string(model.FeedTokenScopeFeed), "what the token grants: feed or caldav")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	scope, err := model.FeedTokenScopeFromString(*scopeStr)
+	if err != nil {
+		return err
+	}
+
+	id, token, err := r.CreateFeedTokenContext(ctx, int32(*ownerId), scope)
+	if err != nil {
+		return err
+	}
+
+	switch scope {
+	case model.FeedTokenScopeCalDAV:
+		_, _ = fmt.Fprintf(os.Stdout, "id:       %s\nurl:      /caldav/\npassword: %s\n", id, token)
+	default:
+		_, _ = fmt.Fprintf(os.Stdout, "id:   %s\nfeed: /feeds/%s.ics\n", id, token)
+	}
+	return nil
+}
+
+func revokeFeedToken(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("feed-revoke", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	idStr := flags.String("id", "", "feed token id")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+	if *idStr == "" {
+		return errors.New("-id is required")
+	}
+
+	id, err := uuid.Parse(*idStr)
+	if err != nil {
+		return err
+	}
+
+	return r.RevokeFeedTokenContext(ctx, int32(*ownerId), id)
+}
+
+func listFeedTokens(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("feed-list", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	tokens, err := r.FeedTokensContext(ctx, int32(*ownerId))
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		revoked := "-"
+		if token.RevokedAt != nil {
+			revoked = token.RevokedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(
+			os.Stdout,
+			"%s  %-6s  created %s  revoked %s\n",
+			token.Id,
+			token.Scope,
+			token.CreatedAt.Format(time.RFC3339),
+			revoked,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/ical"
+)
+
+func exportICS(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("export-ics", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	out := flags.String("out", "-", "output file, - for standard output")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	tasks, err := r.ExportTasksContext(ctx, int32(*ownerId))
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	err = ical.Encode(w, tasks, time.Now().UTC())
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func importICS(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("import-ics", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id the tasks are imported for")
+	in := flags.String("in", "-", "input file, - for standard input")
+	defaultDue := flags.Duration("default-due", 0, "deadline of tasks without one, from now; 0 requires one")
+	tz := flags.String("tz", "UTC", "time zone of floating times")
+	conflictStr := flags.String("conflict", string(model.ImportConflictSkip), "what to do with existing ids: skip, overwrite or duplicate")
+	dryRun := flags.Bool("dry-run", false, "validate without writing")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		return err
+	}
+
+	conflict, err := model.ImportConflictFromString(*conflictStr)
+	if err != nil {
+		return err
+	}
+
+	var defaults ical.Defaults
+	if *defaultDue != 0 {
+		defaults.Deadline = time.Now().Add(*defaultDue).UTC()
+	}
+
+	f, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	tasks, todoErrors, err := ical.Decode(f, int32(*ownerId), defaults, loc)
+	if err != nil {
+		return err
+	}
+
+	report, err := r.ImportTasksContext(ctx, int32(*ownerId), tasks, conflict, *dryRun || len(todoErrors) != 0)
+	if err != nil {
+		return err
+	}
+	report.Errors = append(todoErrors, report.Errors...)
+	report.DryRun = *dryRun
+
+	return printReport(report)
+}
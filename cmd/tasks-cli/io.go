@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+// openInput opens path for reading, "-" being the standard input.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// createOutput creates path for writing, "-" being the standard output.
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// printSummary prints what the tasks read for an import hold.
+func printSummary(tasks []*model.ImportTask) {
+	var roots, checklistItems, images int
+	tags := make(map[string]bool)
+	for _, t := range tasks {
+		if t.Task.ParentId == nil {
+			roots++
+		}
+		checklistItems += len(t.Task.Checklist)
+		images += len(t.Task.ExternalImages)
+		for _, tag := range t.Task.Tags {
+			tags[tag.Name] = true
+		}
+	}
+
+	fmt.Printf(
+		"read: %d tasks, %d top-level, %d checklist items, %d attachments, %d tags\n",
+		len(tasks), roots, checklistItems, images, len(tags),
+	)
+}
+
+// printReport writes a human-readable import report to the standard output.
+func printReport(report *model.ImportReport) error {
+	switch {
+	case report.DryRun:
+		fmt.Println("dry run, nothing was written")
+	case !report.Applied:
+		fmt.Println("import failed, nothing was written")
+	}
+
+	fmt.Printf("created: %d, updated: %d, skipped: %d\n", report.Created, report.Updated, report.Skipped)
+
+	for _, e := range report.Errors {
+		fmt.Println("error:", e.Error())
+	}
+
+	if len(report.Errors) != 0 {
+		return fmt.Errorf("%d tasks could not be imported", len(report.Errors))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"time"
+
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/jsondoc"
+)
+
+var errOwnerRequired = errors.New("-owner is required")
+
+func exportJSON(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("export-json", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id")
+	out := flags.String("out", "-", "output file, - for standard output")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	tasks, err := r.ExportTasksContext(ctx, int32(*ownerId))
+	if err != nil {
+		return err
+	}
+
+	w, err := createOutput(*out)
+	if err != nil {
+		return err
+	}
+
+	err = jsondoc.Encode(w, jsondoc.New(int32(*ownerId), tasks, time.Now().UTC()))
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+func importJSON(ctx context.Context, r *pgrepository.Repository, args []string) error {
+	flags := flag.NewFlagSet("import-json", flag.ExitOnError)
+	ownerId := flags.Int("owner", 0, "owner id the tasks are imported for")
+	in := flags.String("in", "-", "input file, - for standard input")
+	conflictStr := flags.String("conflict", string(model.ImportConflictSkip), "what to do with existing ids: skip, overwrite or duplicate")
+	dryRun := flags.Bool("dry-run", false, "validate without writing")
+	_ = flags.Parse(args)
+
+	if *ownerId == 0 {
+		return errOwnerRequired
+	}
+
+	conflict, err := model.ImportConflictFromString(*conflictStr)
+	if err != nil {
+		return err
+	}
+
+	f, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	doc, err := jsondoc.Decode(f)
+	if err != nil {
+		return err
+	}
+
+	tasks, err := doc.ImportTasks()
+	if err != nil {
+		return err
+	}
+
+	report, err := r.ImportTasksContext(ctx, int32(*ownerId), tasks, conflict, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	return printReport(report)
+}
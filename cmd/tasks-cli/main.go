@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/config"
+	pgconnection "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/connection/postgres"
+	pgmigration "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/migration/postgres"
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/env"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/markdown"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/todoist"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/todotxt"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/trello"
+	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
+)
+
+// command is a subcommand of the CLI. It parses its own flags from args.
+type command struct {
+	summary string
+	run     func(ctx context.Context, r *pgrepository.Repository, args []string) error
+}
+
+var commands = map[string]command{
+	"export-json":    {"export the tasks of an owner to a JSON document", exportJSON},
+	"import-json":    {"import a JSON document into the tasks of an owner", importJSON},
+	"export-csv":     {"export a filtered task listing to CSV", exportCSV},
+	"import-csv":     {"import tasks from CSV with a column mapping", importCSV},
+	"export-ics":     {"export the tasks of an owner as iCalendar VTODOs", exportICS},
+	"import-ics":     {"import iCalendar VTODOs into the tasks of an owner", importICS},
+	"export-todotxt": {"export tasks or a subtree as todo.txt", exportText("export-todotxt", todotxt.Write)},
+	"import-todotxt": {"import todo.txt lines into the tasks of an owner", importText("import-todotxt", todotxt.Read)},
+	"export-md":      {"export tasks or a subtree as a Markdown checklist", exportText("export-md", markdown.Write)},
+	"import-md":      {"import a nested Markdown checklist into the tasks of an owner", importText("import-md", withoutOwner(markdown.Read))},
+	"import-trello":  {"import a Trello board export into the tasks of an owner", importText("import-trello", trello.Read)},
+	"import-todoist": {"import a Todoist backup into the tasks of an owner", importText("import-todoist", todoist.Read)},
+	"feed-create":    {"create an ICS feed or CalDAV token for an owner", createFeedToken},
+	"feed-revoke":    {"revoke an ICS feed or CalDAV token", revokeFeedToken},
+	"feed-list":      {"list the ICS feed and CalDAV tokens of an owner", listFeedTokens},
+}
+
+func main() {
+	log := slog.Default()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	// init environment variables from .env file
+	env.MustLoadEnv()
+
+	cfg := config.MustLoadConfig()
+
+	repository, closeDatabase, err := connect(cfg)
+	if err != nil {
+		log.Error("cannot connect to database", slogattr.Err(err))
+		os.Exit(1)
+	}
+	defer closeDatabase()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	err = cmd.run(ctx, repository, os.Args[2:])
+	if err != nil {
+		log.Error("command failed", slog.String("command", os.Args[1]), slogattr.Err(err))
+		closeDatabase()
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, name := range names {
+		_, _ = fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].summary)
+	}
+}
+
+// connect opens the database of the service. Migrations are the service's
+// job, so the schema has to be at the latest migration already.
+func connect(cfg *config.Config) (*pgrepository.Repository, func(), error) {
+	wf, err := newWorkflow(cfg.Workflow)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	database, err := pgconnection.NewDatabase(
+		cfg.PostgreSQL.Host,
+		cfg.PostgreSQL.Port,
+		cfg.PostgreSQL.User,
+		cfg.PostgreSQL.Password,
+		cfg.PostgreSQL.DBName,
+		cfg.PostgreSQL.SSLMode,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := pgmigration.NewMigrator(os.DirFS(cfg.Migrations.Path), ".")
+	if err != nil {
+		_ = database.DB().Close()
+		return nil, nil, err
+	}
+	defer func() {
+		_ = migrator.Close()
+	}()
+
+	err = migrator.CheckVersion(database.DB())
+	if err != nil {
+		_ = database.DB().Close()
+		return nil, nil, err
+	}
+
+	closeDatabase := func() {
+		_ = database.DB().Close()
+	}
+
+	return pgrepository.NewRepository(database.DB(), wf), closeDatabase, nil
+}
+
+// newWorkflow builds the workflow the service runs with, so that imports
+// validate status changes the same way.
+func newWorkflow(cfg config.Workflow) (*workflow.Workflow, error) {
+	if cfg.Transitions == "" {
+		return workflow.Default(), nil
+	}
+	return workflow.Parse(cfg.Transitions)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"time"
+
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// textWriter and textReader convert tasks to and from a plain text format
+// such as todo.txt or a Markdown checklist. A reader derives the ids it finds
+// in the input per owner.
+type (
+	textWriter func(w io.Writer, tasks []*model.Task, loc *time.Location) error
+	textReader func(r io.Reader, ownerId int32, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error)
+)
+
+// withoutOwner adapts a reader whose input carries no ids.
+func withoutOwner(
+	read func(r io.Reader, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error),
+) textReader {
+	return func(r io.Reader, _ int32, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+		return read(r, defaultDeadline, loc)
+	}
+}
+
+var errOwnerOrRootRequired = errors.New("-owner or -root is required")
+
+// exportText makes a command exporting all the tasks of an owner, or a
+// subtree, with write.
+func exportText(name string, write textWriter) func(context.Context, *pgrepository.Repository, []string) error {
+	return func(ctx context.Context, r *pgrepository.Repository, args []string) error {
+		flags := flag.NewFlagSet(name, flag.ExitOnError)
+		ownerId := flags.Int("owner", 0, "owner id whose tasks are exported")
+		rootStr := flags.String("root", "", "id of the task whose subtree is exported instead")
+		out := flags.String("out", "-", "output file, - for standard output")
+		tz := flags.String("tz", "UTC", "time zone dates are written in")
+		_ = flags.Parse(args)
+
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			return err
+		}
+
+		var tasks []*model.Task
+		switch {
+		case *rootStr != "":
+			rootId, err := uuid.Parse(*rootStr)
+			if err != nil {
+				return err
+			}
+			tasks, err = r.ExportSubtreeContext(ctx, rootId)
+			if err != nil {
+				return err
+			}
+		case *ownerId != 0:
+			tasks, err = r.ExportTasksContext(ctx, int32(*ownerId))
+			if err != nil {
+				return err
+			}
+		default:
+			return errOwnerOrRootRequired
+		}
+
+		w, err := createOutput(*out)
+		if err != nil {
+			return err
+		}
+
+		err = write(w, tasks, loc)
+		if err != nil {
+			_ = w.Close()
+			return err
+		}
+
+		return w.Close()
+	}
+}
+
+// importText makes a command importing tasks read with read, optionally
+// under an existing task.
+func importText(name string, read textReader) func(context.Context, *pgrepository.Repository, []string) error {
+	return func(ctx context.Context, r *pgrepository.Repository, args []string) error {
+		flags := flag.NewFlagSet(name, flag.ExitOnError)
+		ownerId := flags.Int("owner", 0, "owner id the tasks are imported for")
+		in := flags.String("in", "-", "input file, - for standard input")
+		parentStr := flags.String("parent", "", "id of an existing task the top-level tasks go under")
+		defaultDue := flags.Duration("default-due", 0, "deadline of tasks without one, from now; 0 requires one")
+		tz := flags.String("tz", "UTC", "time zone of dates")
+		conflictStr := flags.String("conflict", string(model.ImportConflictSkip), "what to do with existing ids: skip, overwrite or duplicate")
+		dryRun := flags.Bool("dry-run", false, "validate without writing")
+		_ = flags.Parse(args)
+
+		if *ownerId == 0 {
+			return errOwnerRequired
+		}
+
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			return err
+		}
+
+		conflict, err := model.ImportConflictFromString(*conflictStr)
+		if err != nil {
+			return err
+		}
+
+		var parentId *uuid.UUID
+		if *parentStr != "" {
+			id, err := uuid.Parse(*parentStr)
+			if err != nil {
+				return err
+			}
+			parentId = &id
+		}
+
+		var defaultDeadline time.Time
+		if *defaultDue != 0 {
+			defaultDeadline = time.Now().Add(*defaultDue).UTC()
+		}
+
+		f, err := openInput(*in)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+
+		tasks, readErrors, err := read(f, int32(*ownerId), defaultDeadline, loc)
+		if err != nil {
+			return err
+		}
+
+		if parentId != nil {
+			for _, t := range tasks {
+				if t.Task.ParentId == nil {
+					t.Task.ParentId = parentId
+				}
+			}
+		}
+
+		if *dryRun {
+			printSummary(tasks)
+		}
+
+		report, err := r.ImportTasksContext(ctx, int32(*ownerId), tasks, conflict, *dryRun || len(readErrors) != 0)
+		if err != nil {
+			return err
+		}
+		report.Errors = append(readErrors, report.Errors...)
+		report.DryRun = *dryRun
+
+		return printReport(report)
+	}
+}
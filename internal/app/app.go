@@ -3,17 +3,25 @@ package app
 import (
 	"fmt"
 	grpcapp "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/app/grpc"
+	httpapp "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/app/http"
+	schedulerapp "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/app/scheduler"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/config"
 	pgconnection "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/connection/postgres"
 	pgmigration "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/migration/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/notifier"
+	filenotifier "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/notifier/file"
+	slognotifier "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/notifier/slog"
 	"log/slog"
 	"os"
 )
 
 type App struct {
-	grpcApp  *grpcapp.App
-	migrator *pgmigration.Migrator
-	database *pgconnection.Database
+	grpcApp      *grpcapp.App
+	httpApp      *httpapp.App
+	schedulerApp *schedulerapp.App
+	migrator     *pgmigration.Migrator
+	database     *pgconnection.Database
 }
 
 func NewApp(log *slog.Logger, cfg *config.Config) (*App, error) {
@@ -49,17 +57,71 @@ func NewApp(log *slog.Logger, cfg *config.Config) (*App, error) {
 
 	log.Info("migrations applied")
 
-	grpcApp := grpcapp.NewApp(log, int(cfg.GRPC.Port), database)
+	wf, err := newWorkflow(cfg.Workflow)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	grpcApp := grpcapp.NewApp(log, cfg.GRPC, database, wf)
+
+	httpApp := httpapp.NewApp(log, cfg.HTTP, database, wf)
+
+	n, err := newNotifier(log, cfg.Notifier)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schedulerApp, err := schedulerapp.NewApp(log, cfg.Scheduler, n, database, wf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	return &App{
-		grpcApp:  grpcApp,
-		migrator: migrator,
-		database: database,
+		grpcApp:      grpcApp,
+		httpApp:      httpApp,
+		schedulerApp: schedulerApp,
+		migrator:     migrator,
+		database:     database,
 	}, nil
 }
 
+func newNotifier(log *slog.Logger, cfg config.Notifier) (notifier.Notifier, error) {
+	switch cfg.Type {
+	case "log":
+		return slognotifier.NewNotifier(log), nil
+	case "file":
+		return filenotifier.NewNotifier(cfg.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier type: %s", cfg.Type)
+	}
+}
+
+func newWorkflow(cfg config.Workflow) (*workflow.Workflow, error) {
+	if cfg.Transitions == "" {
+		return workflow.Default(), nil
+	}
+	return workflow.Parse(cfg.Transitions)
+}
+
+// Run serves gRPC and HTTP until either server fails or both are stopped.
 func (a *App) Run() error {
-	return a.grpcApp.Run()
+	a.schedulerApp.Run()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- a.httpApp.Run()
+	}()
+	go func() {
+		errs <- a.grpcApp.Run()
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (a *App) Stop() {
@@ -68,4 +130,6 @@ func (a *App) Stop() {
 		_ = a.database.DB().Close()
 	}()
 	a.grpcApp.Stop()
+	a.httpApp.Stop()
+	a.schedulerApp.Stop()
 }
@@ -3,8 +3,10 @@ package app
 import (
 	"context"
 	"fmt"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/config"
 	pgconnection "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/connection/postgres"
 	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
 	apiserver "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/grpc"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
@@ -20,7 +22,7 @@ type App struct {
 	grpcServer *grpc.Server
 }
 
-func NewApp(log *slog.Logger, port int, database *pgconnection.Database) *App {
+func NewApp(log *slog.Logger, cfg config.GRPC, database *pgconnection.Database, wf *workflow.Workflow) *App {
 	loggingOpts := []logging.Option{
 		logging.WithLogOnEvents(
 			logging.StartCall,
@@ -43,12 +45,12 @@ func NewApp(log *slog.Logger, port int, database *pgconnection.Database) *App {
 		),
 	)
 
-	r := pgrepository.NewRepository(database.DB())
+	r := pgrepository.NewRepository(database.DB(), wf)
 
-	apiserver.RegisterServer(grpcServer, log, r)
+	apiserver.RegisterServer(grpcServer, log, r, cfg.SuggestPossibleDeadline)
 
 	return &App{
-		port:       port,
+		port:       int(cfg.Port),
 		grpcServer: grpcServer,
 	}
 }
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/config"
+	pgconnection "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/connection/postgres"
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	apiserver "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/http"
+)
+
+// shutdownTimeout bounds how long Stop waits for requests in flight.
+const shutdownTimeout = 10 * time.Second
+
+type App struct {
+	httpServer *http.Server
+}
+
+func NewApp(log *slog.Logger, cfg config.HTTP, database *pgconnection.Database, wf *workflow.Workflow) *App {
+	r := pgrepository.NewRepository(database.DB(), wf)
+
+	return &App{
+		httpServer: &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.Port),
+			Handler:      apiserver.NewHandler(log, r),
+			ReadTimeout:  cfg.Timeout,
+			WriteTimeout: cfg.Timeout,
+		},
+	}
+}
+
+func (a *App) Run() error {
+	const op = "app.http.Run"
+
+	err := a.httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (a *App) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	_ = a.httpServer.Shutdown(ctx)
+}
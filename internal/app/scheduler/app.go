@@ -0,0 +1,63 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/config"
+	pgconnection "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/connection/postgres"
+	pgrepository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/notifier"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/scheduler"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/scheduler/jobs/overdue"
+)
+
+var (
+	ErrInvalidInterval     = errors.New("scheduler interval must be positive")
+	ErrInvalidBatchSize    = errors.New("scheduler batch size must be positive")
+	ErrInvalidBatchTimeout = errors.New("scheduler batch timeout must be positive")
+)
+
+type App struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewApp(
+	log *slog.Logger,
+	cfg config.Scheduler,
+	n notifier.Notifier,
+	database *pgconnection.Database,
+	wf *workflow.Workflow,
+) (*App, error) {
+	const op = "app.scheduler.NewApp"
+
+	if cfg.OverdueInterval <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidInterval)
+	}
+	if cfg.BatchSize == 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidBatchSize)
+	}
+	if cfg.BatchTimeout <= 0 {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidBatchTimeout)
+	}
+
+	r := pgrepository.NewRepository(database.DB(), wf)
+
+	s := scheduler.NewScheduler(log)
+
+	s.Add("overdue", cfg.OverdueInterval, overdue.MakeOverdueJob(log, r, n, cfg.BatchSize, cfg.BatchTimeout, cfg.UrgentWindow))
+
+	return &App{
+		scheduler: s,
+	}, nil
+}
+
+func (a *App) Run() {
+	a.scheduler.Start()
+}
+
+func (a *App) Stop() {
+	a.scheduler.Stop()
+}
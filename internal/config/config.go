@@ -10,11 +10,23 @@ type Config struct {
 	GRPC       GRPC       `env-required:"true"`
 	PostgreSQL PostgreSQL `env-required:"true"`
 	Migrations Migrations `env-required:"true"`
+	HTTP       HTTP
+	Scheduler  Scheduler
+	Notifier   Notifier
+	Workflow   Workflow
 }
 
 type GRPC struct {
 	Port    uint16        `env:"GRPC_PORT" env-default:"6969"`
 	Timeout time.Duration `env:"GRPC_TIMEOUT" env-default:"5s"`
+	// SuggestPossibleDeadline makes Create predict the possible deadline of
+	// tasks created without one from the owner's completed tasks.
+	SuggestPossibleDeadline bool `env:"GRPC_SUGGEST_POSSIBLE_DEADLINE" env-default:"false"`
+}
+
+type HTTP struct {
+	Port    uint16        `env:"HTTP_PORT" env-default:"8080"`
+	Timeout time.Duration `env:"HTTP_TIMEOUT" env-default:"10s"`
 }
 
 type PostgreSQL struct {
@@ -30,6 +42,25 @@ type Migrations struct {
 	Path string `env:"MIGRATIONS_PATH" env-required:"true"`
 }
 
+type Scheduler struct {
+	OverdueInterval time.Duration `env:"SCHEDULER_OVERDUE_INTERVAL" env-default:"1m"`
+	UrgentWindow    time.Duration `env:"SCHEDULER_URGENT_WINDOW" env-default:"0s"`
+	BatchSize       uint64        `env:"SCHEDULER_BATCH_SIZE" env-default:"100"`
+	// BatchTimeout bounds how long a batch keeps its tasks locked while the
+	// notifier runs.
+	BatchTimeout time.Duration `env:"SCHEDULER_BATCH_TIMEOUT" env-default:"30s"`
+}
+
+type Notifier struct {
+	Type     string `env:"NOTIFIER_TYPE" env-default:"log"`
+	FilePath string `env:"NOTIFIER_FILE_PATH" env-default:"./notifications.jsonl"`
+}
+
+type Workflow struct {
+	// Transitions use the format of workflow.Parse; empty means the default workflow.
+	Transitions string `env:"WORKFLOW_TRANSITIONS"`
+}
+
 func MustLoadConfig() *Config {
 	var cfg Config
 
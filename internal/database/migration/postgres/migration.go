@@ -13,6 +13,11 @@ import (
 	"io/fs"
 )
 
+// migrationsTable keeps the version of the schema.
+const migrationsTable = "schema_migrations_tasks"
+
+var ErrSchemaOutdated = errors.New("database schema is not at the latest migration")
+
 type Migrator struct {
 	srcDriver source.Driver
 }
@@ -32,7 +37,7 @@ func (m *Migrator) ApplyMigrations(db *sql.DB, dbName string) error {
 	const op = "database.migration.postgres.ApplyMigrations"
 
 	driver, err := postgres.WithInstance(db, &postgres.Config{
-		MigrationsTable: "schema_migrations_tasks",
+		MigrationsTable: migrationsTable,
 	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -50,6 +55,49 @@ func (m *Migrator) ApplyMigrations(db *sql.DB, dbName string) error {
 	return nil
 }
 
+// CheckVersion fails with ErrSchemaOutdated unless the database is at the
+// latest migration and not dirty. It changes nothing, so tools sharing the
+// database with the service can refuse to run against an old schema.
+func (m *Migrator) CheckVersion(db *sql.DB) error {
+	const op = "database.migration.postgres.CheckVersion"
+
+	latest, err := m.latestVersion()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var version uint
+	var dirty bool
+	err = db.QueryRow("SELECT version, dirty FROM "+migrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", op, ErrSchemaOutdated, err)
+	}
+	if dirty || version != latest {
+		return fmt.Errorf("%s: %w: at %d (dirty %t), latest is %d", op, ErrSchemaOutdated, version, dirty, latest)
+	}
+
+	return nil
+}
+
+// latestVersion returns the version of the last migration file.
+func (m *Migrator) latestVersion() (uint, error) {
+	version, err := m.srcDriver.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := m.srcDriver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
 func (m *Migrator) Close() error {
 	return m.srcDriver.Close()
 }
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+type subtaskProgress struct {
+	done  int
+	total int
+}
+
+// checklistsContext loads the checklists of all the given tasks with a single
+// query.
+func (r *Repository) checklistsContext(
+	ctx context.Context,
+	tx *sql.Tx,
+	taskIds []uuid.UUID,
+) (map[uuid.UUID][]*model.ChecklistItem, error) {
+	checklists := make(map[uuid.UUID][]*model.ChecklistItem, len(taskIds))
+	if len(taskIds) == 0 {
+		return checklists, nil
+	}
+
+	rows, err := r.pgsq.Select("id", "task_id", "text", "is_checked", "rank").
+		From("checklist_item").
+		Where(sq.Eq{"task_id": taskIds}).
+		OrderBy("task_id", "rank").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		item := checklistItemTable{}
+		err = rows.Scan(&item.Id, &item.TaskId, &item.Text, &item.IsChecked, &item.Rank)
+		if err != nil {
+			return nil, err
+		}
+		checklists[item.TaskId] = append(checklists[item.TaskId], &model.ChecklistItem{
+			Id:        item.Id,
+			TaskId:    item.TaskId,
+			Text:      item.Text,
+			IsChecked: item.IsChecked,
+			Rank:      item.Rank,
+		})
+	}
+
+	return checklists, rows.Err()
+}
+
+// subtaskProgressContext counts the done and the not canceled live direct
+// subtasks of all the given tasks with a single query.
+func (r *Repository) subtaskProgressContext(
+	ctx context.Context,
+	tx *sql.Tx,
+	taskIds []uuid.UUID,
+) (map[uuid.UUID]subtaskProgress, error) {
+	progress := make(map[uuid.UUID]subtaskProgress, len(taskIds))
+	if len(taskIds) == 0 {
+		return progress, nil
+	}
+
+	rows, err := r.pgsq.Select("parent_id").
+		Column("COUNT(*) FILTER (WHERE progress_status = ?)", progressStatusDone).
+		Column("COUNT(*) FILTER (WHERE progress_status <> ?)", progressStatusCanceled).
+		From("task").
+		Where(sq.Eq{"parent_id": taskIds}).
+		Where(sq.Eq{"deleted_at": nil}).
+		GroupBy("parent_id").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var parentId uuid.UUID
+		var p subtaskProgress
+		err = rows.Scan(&parentId, &p.done, &p.total)
+		if err != nil {
+			return nil, err
+		}
+		progress[parentId] = p
+	}
+
+	return progress, rows.Err()
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+var ErrVersionMismatch = errors.New("task was changed since the given version")
+
+// DeleteTaskContext soft-deletes the task and all its descendants. With a
+// non-nil version the task is only deleted while it is still at that version.
+func (r *Repository) DeleteTaskContext(ctx context.Context, id uuid.UUID, version *int64) error {
+	const op = "repository.DeleteTask"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = r.lockProgressStatus(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if version != nil {
+		err = r.checkVersionContext(ctx, tx, id, *version)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	ids, err := r.subtreeIdsContext(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now().UTC()
+	_, err = r.pgsq.Update("task").
+		Set("deleted_at", now).
+		Set("modified_at", now).
+		Where(sq.Eq{"id": ids}).
+		Where(sq.Eq{"deleted_at": nil}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// checkVersionContext fails with ErrVersionMismatch unless the stored task is
+// at version. The task row must already be locked.
+func (r *Repository) checkVersionContext(ctx context.Context, tx *sql.Tx, id uuid.UUID, version int64) error {
+	var stored int64
+
+	err := r.pgsq.Select("version").
+		From("task").
+		Where(sq.Eq{"id": id}).
+		RunWith(tx).
+		QueryRowContext(ctx).
+		Scan(&stored)
+	if err != nil {
+		return err
+	}
+
+	if stored != version {
+		return ErrVersionMismatch
+	}
+
+	return nil
+}
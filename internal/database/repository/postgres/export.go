@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// ExportTasksContext returns all the tasks of the owner in any status with
+// their relations, in rank order. Root tasks have a nil ParentId.
+func (r *Repository) ExportTasksContext(ctx context.Context, ownerId int32) ([]*model.Task, error) {
+	const op = "repository.ExportTasks"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := r.selectTasks().
+		Where(sq.Eq{"task.owner_id": ownerId}).
+		OrderBy("task.rank", "task.id")
+
+	tasks, err := r.queryTasksContext(ctx, tx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	err = r.attachRelationsContext(ctx, tx, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tasks, nil
+}
+
+// ExportTaskContext returns the task with its relations.
+func (r *Repository) ExportTaskContext(ctx context.Context, id uuid.UUID) (*model.Task, error) {
+	const op = "repository.ExportTask"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	tasks, err := r.queryTasksContext(ctx, tx, r.selectTasks().Where(sq.Eq{"task.id": id}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, sql.ErrNoRows)
+	}
+	err = r.attachRelationsContext(ctx, tx, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tasks[0], nil
+}
+
+// ExportSubtreeContext returns the task and all its descendants with their
+// relations, in rank order.
+func (r *Repository) ExportSubtreeContext(ctx context.Context, rootId uuid.UUID) ([]*model.Task, error) {
+	const op = "repository.ExportSubtree"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := r.selectTasks().
+		PrefixExpr(subtreesOf([]uuid.UUID{rootId})).
+		Join("tree ON tree.id = task.id").
+		OrderBy("task.rank", "task.id")
+
+	tasks, err := r.queryTasksContext(ctx, tx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	err = r.attachRelationsContext(ctx, tx, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, sql.ErrNoRows)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tasks, nil
+}
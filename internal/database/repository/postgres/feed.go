@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+var ErrFeedTokenNotFound = errors.New("feed token not found")
+
+// CreateFeedTokenContext creates a secret token of the given scope for the
+// calendar clients of the owner. The token is only returned here, the
+// database keeps its hash.
+func (r *Repository) CreateFeedTokenContext(
+	ctx context.Context,
+	ownerId int32,
+	scope model.FeedTokenScope,
+) (uuid.UUID, string, error) {
+	const op = "repository.CreateFeedToken"
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	_, err = r.pgsq.Insert("feed_token").
+		Columns("id", "owner_id", "scope", "token_hash").
+		Values(id, ownerId, scope, hashFeedToken(token)).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, token, nil
+}
+
+// RevokeFeedTokenContext stops a token of the owner from granting access.
+// Tokens of other owners are not found.
+func (r *Repository) RevokeFeedTokenContext(ctx context.Context, ownerId int32, id uuid.UUID) error {
+	const op = "repository.RevokeFeedToken"
+
+	result, err := r.pgsq.Update("feed_token").
+		Set("revoked_at", time.Now().UTC()).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"owner_id": ownerId}).
+		Where(sq.Eq{"revoked_at": nil}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return expectAffected(op, result, ErrFeedTokenNotFound)
+}
+
+// FeedTokensContext returns the feed tokens of the owner, revoked ones
+// included, latest first.
+func (r *Repository) FeedTokensContext(ctx context.Context, ownerId int32) ([]*model.FeedToken, error) {
+	const op = "repository.FeedTokens"
+
+	rows, err := r.pgsq.Select("id", "owner_id", "scope", "created_at", "revoked_at").
+		From("feed_token").
+		Where(sq.Eq{"owner_id": ownerId}).
+		OrderBy("created_at DESC", "id").
+		RunWith(r.db).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	tokens := make([]*model.FeedToken, 0)
+	for rows.Next() {
+		token := &model.FeedToken{}
+		var scope string
+		err = rows.Scan(&token.Id, &token.OwnerId, &scope, &token.CreatedAt, &token.RevokedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		token.Scope, err = model.FeedTokenScopeFromString(scope)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tokens = append(tokens, token)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// FeedContext returns the open tasks of the owner of a valid feed token by
+// deadline, and the token.
+func (r *Repository) FeedContext(ctx context.Context, token string) (*model.FeedToken, []*model.Task, error) {
+	const op = "repository.Feed"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	feedToken, err := r.validTokenContext(ctx, tx, token, model.FeedTokenScopeFeed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := r.selectTasks().
+		Where(sq.Eq{"task.owner_id": feedToken.OwnerId}).
+		Where(sq.Eq{"task.progress_status": openProgressStatuses}).
+		OrderBy("task.deadline", "task.id")
+
+	tasks, err := r.queryTasksContext(ctx, tx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	err = r.attachRelationsContext(ctx, tx, tasks)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return feedToken, tasks, nil
+}
+
+// TokenOwnerContext returns the owner of a valid token of the given scope.
+func (r *Repository) TokenOwnerContext(ctx context.Context, token string, scope model.FeedTokenScope) (int32, error) {
+	const op = "repository.TokenOwner"
+
+	feedToken, err := r.validTokenContext(ctx, r.db, token, scope)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return feedToken.OwnerId, nil
+}
+
+// validTokenContext finds an unrevoked token of the given scope, or fails with
+// ErrFeedTokenNotFound.
+func (r *Repository) validTokenContext(
+	ctx context.Context,
+	runner sq.BaseRunner,
+	token string,
+	scope model.FeedTokenScope,
+) (*model.FeedToken, error) {
+	feedToken := &model.FeedToken{Scope: scope}
+
+	err := r.pgsq.Select("id", "owner_id", "created_at").
+		From("feed_token").
+		Where(sq.Eq{"token_hash": hashFeedToken(token)}).
+		Where(sq.Eq{"scope": scope}).
+		Where(sq.Eq{"revoked_at": nil}).
+		RunWith(runner).
+		QueryRowContext(ctx).
+		Scan(&feedToken.Id, &feedToken.OwnerId, &feedToken.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeedTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return feedToken, nil
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// expectAffected returns notFound wrapped with op when the statement changed
+// no rows.
+func expectAffected(op string, result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, notFound)
+	}
+
+	return nil
+}
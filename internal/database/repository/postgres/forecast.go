@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+// forecastWindow is the number of past days of completed tasks forecasts are
+// based on.
+const forecastWindow = 28
+
+// forecastWeight counts every task as at least one unit of work, so unweighted
+// tasks still move forecasts.
+const forecastWeight = "GREATEST(task.weight, 1)"
+
+// SuggestPossibleDeadlineContext predicts when a new task of the given weight
+// would be done if the owner started it now.
+func (r *Repository) SuggestPossibleDeadlineContext(ctx context.Context, ownerId int32, weight int32) (time.Time, error) {
+	const op = "repository.SuggestPossibleDeadline"
+
+	now := time.Now().UTC()
+
+	throughput, err := r.throughputContext(ctx, ownerId, now, forecastWindow)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	forecast, err := throughput.Forecast(now, int64(max(weight, 1)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return forecast.Expected, nil
+}
+
+// throughputContext returns the weight the owner completed on each of the days
+// days before now, oldest first.
+func (r *Repository) throughputContext(ctx context.Context, ownerId int32, now time.Time, days int) (model.Throughput, error) {
+	today := now.Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -days)
+
+	rows, err := r.pgsq.Select("task.completed_at::date", "SUM("+forecastWeight+")").
+		From("task").
+		Where(sq.Eq{"task.owner_id": ownerId}).
+		Where(sq.Eq{"task.progress_status": progressStatusDone}).
+		Where(sq.Eq{"task.deleted_at": nil}).
+		Where(sq.GtOrEq{"task.completed_at": from}).
+		Where(sq.Lt{"task.completed_at": today}).
+		GroupBy("task.completed_at::date").
+		RunWith(r.db).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	throughput := make(model.Throughput, days)
+	for rows.Next() {
+		var day time.Time
+		var weight float64
+		err = rows.Scan(&day, &weight)
+		if err != nil {
+			return nil, err
+		}
+
+		i := int(day.Sub(from).Hours() / 24)
+		if i >= 0 && i < days {
+			throughput[i] = weight
+		}
+	}
+
+	return throughput, rows.Err()
+}
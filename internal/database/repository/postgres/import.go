@@ -0,0 +1,515 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/rank"
+	"github.com/google/uuid"
+)
+
+// ImportTasksContext creates the tasks for the owner in a single transaction,
+// parents before their children and siblings in the given order. Tasks whose
+// id already exists are handled according to conflict. The import is all or
+// nothing: when any task is invalid the report lists the errors and nothing
+// is written. A dry run validates and counts without writing.
+func (r *Repository) ImportTasksContext(
+	ctx context.Context,
+	ownerId int32,
+	tasks []*model.ImportTask,
+	conflict model.ImportConflict,
+	dryRun bool,
+) (*model.ImportReport, error) {
+	const op = "repository.ImportTasks"
+
+	report := &model.ImportReport{
+		DryRun: dryRun,
+		Errors: make([]*model.ImportError, 0),
+		Ids:    make(map[string]uuid.UUID, len(tasks)),
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	byId := make(map[uuid.UUID]*model.ImportTask, len(tasks))
+	refs := make([]uuid.UUID, 0, 2*len(tasks))
+	for _, t := range tasks {
+		if t.Task.Id != uuid.Nil {
+			if _, ok := byId[t.Task.Id]; ok {
+				report.Errors = append(report.Errors, &model.ImportError{Key: t.Key, Message: "duplicate id"})
+				continue
+			}
+			byId[t.Task.Id] = t
+			refs = append(refs, t.Task.Id)
+		}
+		if t.Task.ParentId != nil {
+			refs = append(refs, *t.Task.ParentId)
+		}
+	}
+
+	existing, err := r.existingTasksContext(ctx, tx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ordered, cyclic := parentsFirst(tasks, byId)
+	for _, t := range cyclic {
+		report.Errors = append(report.Errors, &model.ImportError{Key: t.Key, Message: "parent cycle"})
+	}
+
+	// newIds is the id every imported task gets, skipped ones keep theirs
+	newIds := make(map[*model.ImportTask]uuid.UUID, len(tasks))
+	actions := make(map[*model.ImportTask]string, len(tasks))
+	for _, t := range ordered {
+		action, message := importAction(t, existing, ownerId, conflict, r.workflow)
+		if message != "" {
+			report.Errors = append(report.Errors, &model.ImportError{Key: t.Key, Message: message})
+			continue
+		}
+		actions[t] = action
+
+		id := t.Task.Id
+		if action == importCreate && (id == uuid.Nil || conflict == model.ImportConflictDuplicate) {
+			id, err = uuid.NewRandom()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		newIds[t] = id
+		report.Ids[t.Key] = id
+	}
+
+	for _, t := range ordered {
+		if _, ok := actions[t]; !ok || t.Task.ParentId == nil {
+			continue
+		}
+		parentId := *t.Task.ParentId
+		if parent, ok := byId[parentId]; ok {
+			if _, ok := newIds[parent]; !ok {
+				report.Errors = append(report.Errors, &model.ImportError{Key: t.Key, Message: "parent is not imported"})
+			}
+			continue
+		}
+		if e, ok := existing[parentId]; !ok || e.OwnerId != ownerId || e.DeletedAt != nil {
+			report.Errors = append(report.Errors, &model.ImportError{Key: t.Key, Message: "unknown parent"})
+		}
+	}
+
+	if len(report.Errors) != 0 {
+		return report, nil
+	}
+
+	for _, t := range ordered {
+		switch actions[t] {
+		case importCreate:
+			report.Created++
+		case importUpdate:
+			report.Updated++
+		case importSkip:
+			report.Skipped++
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	now := time.Now().UTC()
+	for _, t := range ordered {
+		action := actions[t]
+		if action == importSkip {
+			continue
+		}
+
+		task, err := importTable(t.Task, newIds[t], ownerId, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, t.Key, err)
+		}
+		if t.Task.ParentId != nil {
+			parentId := *t.Task.ParentId
+			if parent, ok := byId[parentId]; ok {
+				parentId = newIds[parent]
+			}
+			task.ParentId = &parentId
+		}
+
+		if action == importCreate {
+			err = r.insertTaskContext(ctx, tx, task, t.Task.ExternalImages)
+		} else {
+			err = r.overwriteTaskContext(ctx, tx, task, t.Task.ExternalImages)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, t.Key, err)
+		}
+
+		if t.Task.Tags != nil {
+			tagIds, err := r.ensureTagsContext(ctx, tx, ownerId, t.Task.Tags)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", op, t.Key, err)
+			}
+
+			err = r.setTaskTags(ctx, tx, task.Id, tagIds)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", op, t.Key, err)
+			}
+		}
+
+		if t.Task.Checklist != nil {
+			err = r.replaceChecklistContext(ctx, tx, task.Id, t.Task.Checklist)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", op, t.Key, err)
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	report.Applied = true
+
+	return report, nil
+}
+
+// importedTagColor is the color of the tags an import creates.
+const importedTagColor = "#808080"
+
+const (
+	importCreate = "create"
+	importUpdate = "update"
+	importSkip   = "skip"
+)
+
+// importAction decides what to do with an imported task, or why it cannot be
+// imported. Overwriting a task must be a status change the workflow allows.
+func importAction(
+	t *model.ImportTask,
+	existing map[uuid.UUID]*taskTable,
+	ownerId int32,
+	conflict model.ImportConflict,
+	wf *workflow.Workflow,
+) (string, string) {
+	if t.Task.Header == "" {
+		return "", "header is required"
+	}
+	if _, err := progressStatusFromModelProgressStatus(t.Task.ProgressStatus); err != nil {
+		return "", err.Error()
+	}
+
+	e, ok := existing[t.Task.Id]
+	if !ok || conflict == model.ImportConflictDuplicate {
+		return importCreate, ""
+	}
+
+	switch {
+	case e.OwnerId != ownerId:
+		return "", "task belongs to another owner"
+	case e.DeletedAt != nil:
+		return "", "task was deleted"
+	case conflict == model.ImportConflictSkip:
+		return importSkip, ""
+	}
+
+	from, err := model.ProgressStatusFromString(e.ProgressStatus)
+	if err != nil {
+		return "", err.Error()
+	}
+	if err := wf.Validate(from, t.Task.ProgressStatus); err != nil {
+		return "", err.Error()
+	}
+
+	return importUpdate, ""
+}
+
+// parentsFirst orders the tasks so that every task comes after its parent
+// when both are imported, keeping the given order otherwise. Tasks on parent
+// cycles are returned separately.
+func parentsFirst(tasks []*model.ImportTask, byId map[uuid.UUID]*model.ImportTask) ([]*model.ImportTask, []*model.ImportTask) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[*model.ImportTask]int, len(tasks))
+	ordered := make([]*model.ImportTask, 0, len(tasks))
+	cyclic := make([]*model.ImportTask, 0)
+
+	var visit func(t *model.ImportTask) bool
+	visit = func(t *model.ImportTask) bool {
+		switch state[t] {
+		case visiting:
+			return false
+		case visited:
+			return true
+		}
+		state[t] = visiting
+
+		ok := true
+		if t.Task.ParentId != nil {
+			if parent, found := byId[*t.Task.ParentId]; found && parent != t {
+				ok = visit(parent)
+			} else if found {
+				ok = false
+			}
+		}
+
+		state[t] = visited
+		if ok {
+			ordered = append(ordered, t)
+		} else {
+			cyclic = append(cyclic, t)
+		}
+		return ok
+	}
+
+	for _, t := range tasks {
+		visit(t)
+	}
+
+	return ordered, cyclic
+}
+
+// existingTasksContext returns the stored tasks among ids.
+func (r *Repository) existingTasksContext(ctx context.Context, tx *sql.Tx, ids []uuid.UUID) (map[uuid.UUID]*taskTable, error) {
+	existing := make(map[uuid.UUID]*taskTable, len(ids))
+	if len(ids) == 0 {
+		return existing, nil
+	}
+
+	rows, err := r.pgsq.Select("id", "owner_id", "progress_status", "deleted_at").
+		From("task").
+		Where(sq.Eq{"id": ids}).
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		task := &taskTable{}
+		err = rows.Scan(&task.Id, &task.OwnerId, &task.ProgressStatus, &task.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		existing[task.Id] = task
+	}
+
+	return existing, rows.Err()
+}
+
+// ensureTagsContext returns the ids of the owner's tags named like tags,
+// creating the missing ones.
+func (r *Repository) ensureTagsContext(ctx context.Context, tx *sql.Tx, ownerId int32, tags []*model.Tag) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(tags))
+	if len(tags) == 0 {
+		return ids, nil
+	}
+
+	names := make([]string, 0, len(tags))
+	insert := r.pgsq.Insert("tag").
+		Columns("id", "owner_id", "name", "color")
+	for _, tag := range tags {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, tag.Name)
+		insert = insert.Values(id, ownerId, tag.Name, importedTagColor)
+	}
+
+	_, err := insert.
+		Suffix("ON CONFLICT (owner_id, name) DO NOTHING").
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pgsq.Select("id").
+		From("tag").
+		Where(sq.Eq{"owner_id": ownerId}).
+		Where(sq.Eq{"name": names}).
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// replaceChecklistContext replaces the checklist of the task with items, in
+// the given order.
+func (r *Repository) replaceChecklistContext(ctx context.Context, tx *sql.Tx, taskId uuid.UUID, items []*model.ChecklistItem) error {
+	_, err := r.pgsq.Delete("checklist_item").
+		Where(sq.Eq{"task_id": taskId}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	insert := r.pgsq.Insert("checklist_item").
+		Columns("id", "task_id", "text", "is_checked", "rank")
+
+	key := ""
+	for _, item := range items {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return err
+		}
+		key, err = rank.Between(key, "")
+		if err != nil {
+			return err
+		}
+		insert = insert.Values(id, taskId, item.Text, item.IsChecked, key)
+	}
+
+	_, err = insert.RunWith(tx).ExecContext(ctx)
+	return err
+}
+
+// importTable converts an imported task to a row of the owner. Completion
+// timestamps missing for a done or canceled task are set to now.
+func importTable(task *model.Task, id uuid.UUID, ownerId int32, now time.Time) (*taskTable, error) {
+	status, err := progressStatusFromModelProgressStatus(task.ProgressStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &taskTable{
+		Id:               id,
+		Header:           task.Header,
+		Text:             task.Text,
+		Deadline:         task.Deadline.UTC(),
+		ProgressStatus:   string(status),
+		IsUrgent:         task.IsUrgent,
+		IsImportant:      task.IsImportant,
+		OwnerId:          ownerId,
+		PossibleDeadline: task.PossibleDeadline.UTC(),
+		Weight:           task.Weight,
+	}
+
+	switch task.ProgressStatus {
+	case model.ProgressStatusDone:
+		t.CompletedAt = task.CompletedAt
+		if t.CompletedAt == nil {
+			t.CompletedAt = &now
+		}
+	case model.ProgressStatusCanceled:
+		t.CanceledAt = task.CanceledAt
+		if t.CanceledAt == nil {
+			t.CanceledAt = &now
+		}
+	}
+
+	return t, nil
+}
+
+// overwriteTaskContext replaces the fields and external images of a stored
+// task with the ones of task. Like an update, it refuses to move the task under
+// one of its descendants.
+func (r *Repository) overwriteTaskContext(ctx context.Context, tx *sql.Tx, task *taskTable, externalImages []string) error {
+	previousProgressStatus, err := r.lockProgressStatus(ctx, tx, task.Id)
+	if err != nil {
+		return err
+	}
+
+	progressStatus, err := model.ProgressStatusFromString(task.ProgressStatus)
+	if err != nil {
+		return err
+	}
+
+	err = r.workflow.Validate(previousProgressStatus, progressStatus)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkParentContext(ctx, tx, task.Id, task.ParentId)
+	if err != nil {
+		return err
+	}
+
+	newRank, err := r.rankForNewParent(ctx, tx, task.Id, task.OwnerId, task.ParentId)
+	if err != nil {
+		return err
+	}
+
+	query := r.pgsq.Update("task").
+		Set("header", task.Header).
+		Set("text", task.Text).
+		Set("deadline", task.Deadline).
+		Set("progress_status", task.ProgressStatus).
+		Set("is_urgent", task.IsUrgent).
+		Set("is_important", task.IsImportant).
+		Set("parent_id", task.ParentId).
+		Set("possible_deadline", task.PossibleDeadline).
+		Set("weight", task.Weight).
+		Set("completed_at", task.CompletedAt).
+		Set("canceled_at", task.CanceledAt).
+		Set("modified_at", time.Now().UTC())
+	if newRank != "" {
+		query = query.Set("rank", newRank)
+	}
+
+	_, err = query.
+		Where(sq.Eq{"id": task.Id}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.pgsq.Delete("external_image").
+		Where(sq.Eq{"task_id": task.Id}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(externalImages) != 0 {
+		stmt := r.pgsq.Insert("external_image").
+			Columns("url", "task_id")
+
+		for _, url := range externalImages {
+			stmt = stmt.Values(url, task.Id)
+		}
+
+		_, err = stmt.RunWith(tx).ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
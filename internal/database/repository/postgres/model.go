@@ -18,6 +18,12 @@ type taskTable struct {
 	ParentId         *uuid.UUID
 	PossibleDeadline time.Time
 	Weight           int32
+	CompletedAt      *time.Time
+	CanceledAt       *time.Time
+	Rank             string
+	DeletedAt        *time.Time
+	Version          int64
+	ModifiedAt       time.Time
 }
 
 type externalImageTable struct {
@@ -29,15 +35,32 @@ type externalImageTable struct {
 type progressStatus string
 
 const (
+	progressStatusBacklog    progressStatus = "backlog"
 	progressStatusInProgress progressStatus = "in progress"
+	progressStatusBlocked    progressStatus = "blocked"
+	progressStatusOnHold     progressStatus = "on hold"
 	progressStatusCanceled   progressStatus = "canceled"
 	progressStatusDone       progressStatus = "done"
 )
 
+// openProgressStatuses are the statuses of tasks that still need to be worked on.
+var openProgressStatuses = []progressStatus{
+	progressStatusBacklog,
+	progressStatusInProgress,
+	progressStatusBlocked,
+	progressStatusOnHold,
+}
+
 func progressStatusFromModelProgressStatus(s model.ProgressStatus) (progressStatus, error) {
 	switch s {
+	case model.ProgressStatusBacklog:
+		return progressStatusBacklog, nil
 	case model.ProgressStatusInProgress:
 		return progressStatusInProgress, nil
+	case model.ProgressStatusBlocked:
+		return progressStatusBlocked, nil
+	case model.ProgressStatusOnHold:
+		return progressStatusOnHold, nil
 	case model.ProgressStatusCanceled:
 		return progressStatusCanceled, nil
 	case model.ProgressStatusDone:
@@ -46,3 +69,18 @@ func progressStatusFromModelProgressStatus(s model.ProgressStatus) (progressStat
 		return "", nil
 	}
 }
+
+type checklistItemTable struct {
+	Id        uuid.UUID
+	TaskId    uuid.UUID
+	Text      string
+	IsChecked bool
+	Rank      string
+}
+
+type tagTable struct {
+	Id      uuid.UUID
+	OwnerId int32
+	Name    string
+	Color   string
+}
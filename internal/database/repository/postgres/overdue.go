@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// overdueCondition matches open tasks whose deadline passed before now.
+func overdueCondition(now time.Time) sq.And {
+	return sq.And{
+		sq.Lt{"task.deadline": now},
+		sq.Eq{"task.progress_status": openProgressStatuses},
+		sq.Eq{"task.deleted_at": nil},
+	}
+}
+
+// NotifyOverdueTasksContext passes at most limit open tasks whose deadline
+// passed before now and that were not reported since their deadline to notify,
+// and remembers the ones notify succeeded for. Moving the deadline of a reported
+// task later makes it reported again once the new deadline passes. The tasks
+// stay locked in an open transaction while notify runs, so ctx should bound
+// how long a slow notifier may hold them.
+func (r *Repository) NotifyOverdueTasksContext(
+	ctx context.Context,
+	now time.Time,
+	limit uint64,
+	notify func(context.Context, *model.Task) error,
+) (int, error) {
+	const op = "repository.NotifyOverdueTasks"
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := r.pgsq.Select("id", "header", "deadline", "progress_status", "owner_id", "is_urgent", "is_important").
+		From("task").
+		Where(overdueCondition(now)).
+		Where(sq.Or{
+			sq.Eq{"overdue_notified_at": nil},
+			sq.Expr("overdue_notified_at < deadline"),
+		}).
+		OrderBy("deadline").
+		Limit(limit).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tasks := make([]*model.Task, 0)
+	for rows.Next() {
+		task := taskTable{}
+		err = rows.Scan(
+			&task.Id,
+			&task.Header,
+			&task.Deadline,
+			&task.ProgressStatus,
+			&task.OwnerId,
+			&task.IsUrgent,
+			&task.IsImportant,
+		)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		progressStatus, err := model.ProgressStatusFromString(task.ProgressStatus)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+
+		tasks = append(tasks, &model.Task{
+			Id:             task.Id,
+			Header:         task.Header,
+			Deadline:       task.Deadline,
+			ProgressStatus: progressStatus,
+			OwnerId:        task.OwnerId,
+			IsUrgent:       task.IsUrgent,
+			IsImportant:    task.IsImportant,
+		})
+	}
+	err = rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notified := make([]uuid.UUID, 0, len(tasks))
+	var notifyErr error
+	for _, task := range tasks {
+		if err := notify(ctx, task); err != nil {
+			notifyErr = errors.Join(notifyErr, err)
+			continue
+		}
+		notified = append(notified, task.Id)
+	}
+
+	if len(notified) != 0 {
+		_, err = r.pgsq.Update("task").
+			Set("overdue_notified_at", now).
+			Where(sq.Eq{"id": notified}).
+			RunWith(tx).
+			ExecContext(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if notifyErr != nil {
+		return len(notified), fmt.Errorf("%s: %w", op, notifyErr)
+	}
+
+	return len(notified), nil
+}
+
+// EscalateUrgentTasksContext marks open tasks whose deadline is before
+// now+window as urgent and returns how many tasks were changed.
+func (r *Repository) EscalateUrgentTasksContext(ctx context.Context, now time.Time, window time.Duration) (int64, error) {
+	const op = "repository.EscalateUrgentTasks"
+
+	result, err := r.pgsq.Update("task").
+		Set("is_urgent", true).
+		Set("modified_at", now).
+		Where(sq.Eq{"progress_status": openProgressStatuses}).
+		Where(sq.Eq{"is_urgent": false}).
+		Where(sq.Eq{"deleted_at": nil}).
+		Where(sq.Lt{"deadline": now.Add(window)}).
+		RunWith(r.db).
+		ExecContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
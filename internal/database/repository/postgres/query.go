@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// selectTasks selects the columns scanTask expects from the task table,
+// skipping soft-deleted tasks.
+func (r *Repository) selectTasks() sq.SelectBuilder {
+	return r.pgsq.Select(
+		"task.id",
+		"task.header",
+		"task.text",
+		"task.deadline",
+		"task.progress_status",
+		"task.is_urgent",
+		"task.is_important",
+		"task.owner_id",
+		"task.parent_id",
+		"task.possible_deadline",
+		"task.weight",
+		"task.completed_at",
+		"task.canceled_at",
+		"task.rank",
+		"task.version",
+		"task.modified_at").
+		From("task").
+		Where(sq.Eq{"task.deleted_at": nil})
+}
+
+func scanTask(row sq.RowScanner) (*model.Task, error) {
+	task := taskTable{}
+
+	err := row.Scan(
+		&task.Id,
+		&task.Header,
+		&task.Text,
+		&task.Deadline,
+		&task.ProgressStatus,
+		&task.IsUrgent,
+		&task.IsImportant,
+		&task.OwnerId,
+		&task.ParentId,
+		&task.PossibleDeadline,
+		&task.Weight,
+		&task.CompletedAt,
+		&task.CanceledAt,
+		&task.Rank,
+		&task.Version,
+		&task.ModifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	progressStatus, err := model.ProgressStatusFromString(task.ProgressStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Task{
+		Id:               task.Id,
+		Header:           task.Header,
+		Text:             task.Text,
+		Deadline:         task.Deadline,
+		ProgressStatus:   progressStatus,
+		IsUrgent:         task.IsUrgent,
+		IsImportant:      task.IsImportant,
+		OwnerId:          task.OwnerId,
+		ParentId:         task.ParentId,
+		PossibleDeadline: task.PossibleDeadline,
+		Weight:           task.Weight,
+		CompletedAt:      task.CompletedAt,
+		CanceledAt:       task.CanceledAt,
+		Rank:             task.Rank,
+		Version:          task.Version,
+		ModifiedAt:       task.ModifiedAt,
+	}, nil
+}
+
+// likeEscaper escapes the LIKE wildcards with the default backslash escape
+// character, so a search matches its text literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// filterTasks applies the filters of a Tasks listing to query. Zero times and
+// nil pointers disable the corresponding filter.
+func filterTasks(
+	query sq.SelectBuilder,
+	ownerId int32,
+	search *string,
+	deadlineFrom time.Time,
+	deadlineTo time.Time,
+	possibleDeadlineFrom time.Time,
+	possibleDeadlineTo time.Time,
+	progressStatusPtr *string,
+	isUrgent *bool,
+	isImportant *bool,
+	weightFrom *int32,
+	weightTo *int32,
+	isOverdue *bool,
+) sq.SelectBuilder {
+	query = query.Where(sq.Eq{"task.owner_id": ownerId})
+
+	if search != nil && *search != "" {
+		pattern := "%" + likeEscaper.Replace(*search) + "%"
+		query = query.Where(sq.Or{
+			sq.ILike{"task.header": pattern},
+			sq.ILike{"task.text": pattern},
+		})
+	}
+
+	if (deadlineFrom != time.Time{}) {
+		query = query.Where(sq.GtOrEq{"task.deadline": deadlineFrom})
+	}
+
+	if (deadlineTo != time.Time{}) {
+		query = query.Where(sq.LtOrEq{"task.deadline": deadlineTo})
+	}
+
+	if (possibleDeadlineFrom != time.Time{}) {
+		query = query.Where(sq.GtOrEq{"task.possible_deadline": possibleDeadlineFrom})
+	}
+
+	if (possibleDeadlineTo != time.Time{}) {
+		query = query.Where(sq.LtOrEq{"task.possible_deadline": possibleDeadlineTo})
+	}
+
+	if progressStatusPtr != nil {
+		query = query.Where(sq.Eq{"task.progress_status": *progressStatusPtr})
+	}
+
+	if isUrgent != nil {
+		query = query.Where(sq.Eq{"task.is_urgent": *isUrgent})
+	}
+
+	if isImportant != nil {
+		query = query.Where(sq.Eq{"task.is_important": *isImportant})
+	}
+
+	if weightFrom != nil {
+		query = query.Where(sq.GtOrEq{"task.weight": *weightFrom})
+	}
+
+	if weightTo != nil {
+		query = query.Where(sq.LtOrEq{"task.weight": *weightTo})
+	}
+
+	if isOverdue != nil {
+		overdue := overdueCondition(time.Now().UTC())
+		if *isOverdue {
+			query = query.Where(overdue)
+		} else {
+			query = query.Where(sq.Expr("NOT (?)", overdue))
+		}
+	}
+
+	return query
+}
+
+// externalImagesContext loads the external images of all the given tasks with
+// a single query, keeping their insertion order.
+func (r *Repository) externalImagesContext(
+	ctx context.Context,
+	tx *sql.Tx,
+	taskIds []uuid.UUID,
+) (map[uuid.UUID][]string, error) {
+	images := make(map[uuid.UUID][]string, len(taskIds))
+	if len(taskIds) == 0 {
+		return images, nil
+	}
+
+	rows, err := r.pgsq.Select("id", "url", "task_id").
+		From("external_image").
+		Where(sq.Eq{"task_id": taskIds}).
+		OrderBy("id").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var extImg externalImageTable
+		err = rows.Scan(&extImg.Id, &extImg.Url, &extImg.TaskId)
+		if err != nil {
+			return nil, err
+		}
+		images[extImg.TaskId] = append(images[extImg.TaskId], extImg.Url)
+	}
+
+	return images, rows.Err()
+}
+
+// queryTasksContext runs query built on selectTasks and attaches external
+// images.
+func (r *Repository) queryTasksContext(ctx context.Context, tx *sql.Tx, query sq.SelectBuilder) ([]*model.Task, error) {
+	rows, err := query.
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*model.Task, 0)
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		tasks = append(tasks, task)
+		ids = append(ids, task.Id)
+	}
+	err = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := r.externalImagesContext(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		task.ExternalImages = images[task.Id]
+		if task.ExternalImages == nil {
+			task.ExternalImages = make([]string, 0)
+		}
+	}
+
+	return tasks, nil
+}
+
+// attachRelationsContext loads the tags and checklists of the tasks and
+// computes their completion, with one query per table. Only exports and
+// calendars show them, so plain reads do not pay for the queries.
+func (r *Repository) attachRelationsContext(ctx context.Context, tx *sql.Tx, tasks []*model.Task) error {
+	ids := make([]uuid.UUID, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.Id)
+	}
+
+	tags, err := r.tagsContext(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	checklists, err := r.checklistsContext(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	subtasks, err := r.subtaskProgressContext(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		task.Tags = tags[task.Id]
+		if task.Tags == nil {
+			task.Tags = make([]*model.Tag, 0)
+		}
+
+		task.Checklist = checklists[task.Id]
+		if task.Checklist == nil {
+			task.Checklist = make([]*model.ChecklistItem, 0)
+		}
+
+		done, total := subtasks[task.Id].done, subtasks[task.Id].total
+		for _, item := range task.Checklist {
+			if item.IsChecked {
+				done++
+			}
+			total++
+		}
+		task.Completion = model.CompletionPercent(task.ProgressStatus, done, total)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/rank"
+	"github.com/google/uuid"
+)
+
+// appendRank returns a rank placing a new task after all the children of
+// parentId, or after all root tasks of the owner when parentId is nil. It locks
+// the siblings until the end of the transaction.
+func (r *Repository) appendRank(ctx context.Context, tx *sql.Tx, ownerId int32, parentId *uuid.UUID) (string, error) {
+	err := r.lockSiblings(ctx, tx, ownerId, parentId)
+	if err != nil {
+		return "", err
+	}
+
+	var last sql.NullString
+	err = siblingsOf(r.pgsq.Select("MAX(rank)").From("task"), ownerId, parentId).
+		RunWith(tx).
+		QueryRowContext(ctx).
+		Scan(&last)
+	if err != nil {
+		return "", err
+	}
+
+	return rank.Between(last.String, "")
+}
+
+// lockSiblings serialises rank changes among the children of one parent.
+func (r *Repository) lockSiblings(ctx context.Context, tx *sql.Tx, ownerId int32, parentId *uuid.UUID) error {
+	key := fmt.Sprintf("task-siblings:%d", ownerId)
+	if parentId != nil {
+		key = "task-siblings:" + parentId.String()
+	}
+
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", key)
+	return err
+}
+
+// siblingsOf restricts query to the live children of parentId, or to the live
+// root tasks of the owner when parentId is nil.
+func siblingsOf(query sq.SelectBuilder, ownerId int32, parentId *uuid.UUID) sq.SelectBuilder {
+	query = query.Where(sq.Eq{"deleted_at": nil})
+	if parentId != nil {
+		return query.Where(sq.Eq{"parent_id": *parentId})
+	}
+	return query.
+		Where(sq.Eq{"owner_id": ownerId}).
+		Where(sq.Eq{"parent_id": nil})
+}
+
+func sameParent(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// rankForNewParent returns a rank placing the task last among the children of
+// parentId when the task is being moved there from another parent, and an
+// empty string when its parent does not change. A zero ownerId keeps the
+// current owner of the task.
+func (r *Repository) rankForNewParent(
+	ctx context.Context,
+	tx *sql.Tx,
+	id uuid.UUID,
+	ownerId int32,
+	parentId *uuid.UUID,
+) (string, error) {
+	current := taskTable{}
+	err := r.pgsq.Select("owner_id", "parent_id").
+		From("task").
+		Where(sq.Eq{"id": id}).
+		RunWith(tx).
+		QueryRowContext(ctx).
+		Scan(&current.OwnerId, &current.ParentId)
+	if err != nil {
+		return "", err
+	}
+
+	if ownerId == 0 {
+		ownerId = current.OwnerId
+	}
+
+	if sameParent(current.ParentId, parentId) && ownerId == current.OwnerId {
+		return "", nil
+	}
+
+	return r.appendRank(ctx, tx, ownerId, parentId)
+}
@@ -8,19 +8,27 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
 	"github.com/google/uuid"
 )
 
 type Repository struct {
-	db   *sql.DB
-	pgsq sq.StatementBuilderType
+	db       *sql.DB
+	pgsq     sq.StatementBuilderType
+	workflow *workflow.Workflow
 }
 
-func NewRepository(db *sql.DB) *Repository {
+// NewRepository creates a repository validating progress status changes with
+// wf, or with workflow.Default() when wf is nil.
+func NewRepository(db *sql.DB, wf *workflow.Workflow) *Repository {
 	pgsq := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	if wf == nil {
+		wf = workflow.Default()
+	}
 	return &Repository{
-		db:   db,
-		pgsq: pgsq,
+		db:       db,
+		pgsq:     pgsq,
+		workflow: wf,
 	}
 }
 
@@ -66,14 +74,6 @@ func (r *Repository) CreateTaskContext(
 		Weight:           weight,
 	}
 
-	extImgs := make([]externalImageTable, 0, len(externalImages))
-	for _, v := range externalImages {
-		extImgs = append(extImgs, externalImageTable{
-			Url:    v,
-			TaskId: task.Id,
-		})
-	}
-
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
@@ -83,6 +83,28 @@ func (r *Repository) CreateTaskContext(
 		_ = tx.Rollback()
 	}()
 
+	err = r.insertTaskContext(ctx, tx, &task, externalImages)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return task.Id, nil
+}
+
+// insertTaskContext inserts the task last among its siblings, together with
+// its external images.
+func (r *Repository) insertTaskContext(ctx context.Context, tx *sql.Tx, task *taskTable, externalImages []string) error {
+	var err error
+	task.Rank, err = r.appendRank(ctx, tx, task.OwnerId, task.ParentId)
+	if err != nil {
+		return err
+	}
+
 	_, err = r.pgsq.Insert("task").
 		Columns(
 			"id",
@@ -95,7 +117,10 @@ func (r *Repository) CreateTaskContext(
 			"owner_id",
 			"parent_id",
 			"possible_deadline",
-			"weight").
+			"weight",
+			"rank",
+			"completed_at",
+			"canceled_at").
 		Values(
 			task.Id,
 			task.Header,
@@ -107,41 +132,36 @@ func (r *Repository) CreateTaskContext(
 			task.OwnerId,
 			task.ParentId,
 			task.PossibleDeadline,
-			task.Weight).
+			task.Weight,
+			task.Rank,
+			task.CompletedAt,
+			task.CanceledAt).
 		RunWith(tx).
 		ExecContext(ctx)
-
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		return err
 	}
 
-	if len(extImgs) != 0 {
+	if len(externalImages) != 0 {
 		stmt := r.pgsq.Insert("external_image").
 			Columns("url", "task_id")
 
-		for _, v := range extImgs {
-			stmt = stmt.Values(v.Url, v.TaskId)
+		for _, url := range externalImages {
+			stmt = stmt.Values(url, task.Id)
 		}
 
 		_, err = stmt.RunWith(tx).ExecContext(ctx)
 		if err != nil {
-			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+			return err
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
-	}
-
-	return task.Id, nil
+	return nil
 }
 
 func (r *Repository) TaskContext(ctx context.Context, id uuid.UUID) (*model.Task, error) {
 	const op = "repository.Task"
 
-	task := taskTable{}
-
 	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -150,54 +170,21 @@ func (r *Repository) TaskContext(ctx context.Context, id uuid.UUID) (*model.Task
 		_ = tx.Rollback()
 	}()
 
-	err = r.pgsq.Select("id", "header", "text", "deadline", "progress_status", "is_urgent", "is_important", "owner_id", "parent_id", "possible_deadline", "weight").
-		From("task").
-		Where(sq.Eq{"id": id}).
+	task, err := scanTask(r.selectTasks().
+		Where(sq.Eq{"task.id": id}).
 		RunWith(tx).
-		QueryRowContext(ctx).
-		Scan(
-			&task.Id,
-			&task.Header,
-			&task.Text,
-			&task.Deadline,
-			&task.ProgressStatus,
-			&task.IsUrgent,
-			&task.IsImportant,
-			&task.OwnerId,
-			&task.ParentId,
-			&task.PossibleDeadline,
-			&task.Weight,
-		)
+		QueryRowContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	extImgs := make([]externalImageTable, 0)
-	rows, err := r.pgsq.Select("id", "url", "task_id").
-		From("external_image").
-		Where(sq.Eq{"task_id": id}).
-		RunWith(tx).
-		QueryContext(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
-
-	for rows.Next() {
-		var extImg externalImageTable
-		err = rows.Scan(&extImg.Id, &extImg.Url, &extImg.TaskId)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-		extImgs = append(extImgs, extImg)
-	}
-	err = rows.Close()
+	images, err := r.externalImagesContext(ctx, tx, []uuid.UUID{id})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-
-	images := make([]string, 0, len(extImgs))
-	for _, v := range extImgs {
-		images = append(images, v.Url)
+	task.ExternalImages = images[id]
+	if task.ExternalImages == nil {
+		task.ExternalImages = make([]string, 0)
 	}
 
 	err = tx.Commit()
@@ -205,25 +192,7 @@ func (r *Repository) TaskContext(ctx context.Context, id uuid.UUID) (*model.Task
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	progressStatus, err := model.ProgressStatusFromString(task.ProgressStatus)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
-
-	return &model.Task{
-		Id:               task.Id,
-		Header:           task.Header,
-		Text:             task.Text,
-		Deadline:         task.Deadline,
-		ProgressStatus:   progressStatus,
-		IsUrgent:         task.IsUrgent,
-		IsImportant:      task.IsImportant,
-		OwnerId:          task.OwnerId,
-		ParentId:         task.ParentId,
-		PossibleDeadline: task.PossibleDeadline,
-		Weight:           task.Weight,
-		ExternalImages:   images,
-	}, nil
+	return task, nil
 }
 
 func (r *Repository) TasksContext(
@@ -239,6 +208,7 @@ func (r *Repository) TasksContext(
 	isImportant *bool,
 	weightFrom *int32,
 	weightTo *int32,
+	isOverdue *bool,
 ) ([]*model.Task, error) {
 	const op = "repository.Tasks"
 
@@ -250,157 +220,93 @@ func (r *Repository) TasksContext(
 		_ = tx.Rollback()
 	}()
 
-	query := r.pgsq.Select("id", "header", "text", "deadline", "progress_status", "is_urgent", "is_important", "owner_id", "parent_id", "possible_deadline", "weight").
-		From("task").
-		Where(sq.Eq{"owner_id": ownerId})
-
-	if (deadlineFrom != time.Time{}) {
-		query = query.Where(sq.GtOrEq{"deadline": deadlineFrom})
-	}
-
-	if (deadlineTo != time.Time{}) {
-		query = query.Where(sq.LtOrEq{"deadline": deadlineTo})
-	}
-
-	if (possibleDeadlineFrom != time.Time{}) {
-		query = query.Where(sq.GtOrEq{"possible_deadline": possibleDeadlineFrom})
+	query := filterTasks(
+		r.selectTasks(),
+		ownerId,
+		search,
+		deadlineFrom,
+		deadlineTo,
+		possibleDeadlineFrom,
+		possibleDeadlineTo,
+		progressStatusPtr,
+		isUrgent,
+		isImportant,
+		weightFrom,
+		weightTo,
+		isOverdue,
+	)
+
+	// siblings stay together, in the order they were added to their parent
+	query = query.OrderBy("task.parent_id NULLS FIRST", "task.rank", "task.id")
+
+	tasks, err := r.queryTasksContext(ctx, tx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if (possibleDeadlineTo != time.Time{}) {
-		query = query.Where(sq.LtOrEq{"possible_deadline": possibleDeadlineTo})
+	for _, task := range tasks {
+		if task.ParentId == nil {
+			task.ParentId = &uuid.Nil
+		}
 	}
 
-	if progressStatusPtr != nil {
-		query = query.Where(sq.Eq{"progress_status": *progressStatusPtr})
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if isUrgent != nil {
-		query = query.Where(sq.Eq{"is_urgent": *isUrgent})
-	}
+	return tasks, nil
+}
 
-	if isImportant != nil {
-		query = query.Where(sq.Eq{"is_important": *isImportant})
-	}
+func (r *Repository) UpdateTaskContext(ctx context.Context, task *model.Task) error {
+	return r.updateTaskContext(ctx, task, nil)
+}
 
-	if weightFrom != nil {
-		query = query.Where(sq.GtOrEq{"weight": *weightFrom})
-	}
+// UpdateTaskVersionContext updates the task like UpdateTaskContext while it is
+// still at version, and fails with ErrVersionMismatch otherwise.
+func (r *Repository) UpdateTaskVersionContext(ctx context.Context, task *model.Task, version int64) error {
+	return r.updateTaskContext(ctx, task, &version)
+}
 
-	if weightTo != nil {
-		query = query.Where(sq.LtOrEq{"weight": *weightTo})
-	}
+func (r *Repository) updateTaskContext(ctx context.Context, task *model.Task, version *int64) error {
+	const op = "repository.UpdateTask"
 
-	rows, err := query.
-		RunWith(tx).
-		QueryContext(ctx)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	defer func() {
-		_ = rows.Close()
+		_ = tx.Rollback()
 	}()
 
-	tasks := make([]*model.Task, 0)
-	for rows.Next() {
-		task := taskTable{}
-		err = rows.Scan(
-			&task.Id,
-			&task.Header,
-			&task.Text,
-			&task.Deadline,
-			&task.ProgressStatus,
-			&task.IsUrgent,
-			&task.IsImportant,
-			&task.OwnerId,
-			&task.ParentId,
-			&task.PossibleDeadline,
-			&task.Weight,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		if task.ParentId == nil {
-			task.ParentId = &uuid.Nil
-		}
-
-		progressStatus, err := model.ProgressStatusFromString(task.ProgressStatus)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-
-		tasks = append(tasks, &model.Task{
-			Id:               task.Id,
-			Header:           task.Header,
-			Text:             task.Text,
-			Deadline:         task.Deadline,
-			ProgressStatus:   progressStatus,
-			IsUrgent:         task.IsUrgent,
-			IsImportant:      task.IsImportant,
-			OwnerId:          task.OwnerId,
-			ParentId:         task.ParentId,
-			PossibleDeadline: task.PossibleDeadline,
-			Weight:           task.Weight,
-		})
-	}
-	err = rows.Err()
+	previousProgressStatus, err := r.lockProgressStatus(ctx, tx, task.Id)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	images := make([]string, 0)
-	for _, v := range tasks {
-		rows, err := r.pgsq.Select("id", "url", "task_id").
-			From("external_image").
-			Where(sq.Eq{"task_id": v.Id}).
-			RunWith(tx).
-			QueryContext(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
-		}
-		for rows.Next() {
-			var extImg externalImageTable
-			err = rows.Scan(&extImg.Id, &extImg.Url, &extImg.TaskId)
-			if err != nil {
-				return nil, fmt.Errorf("%s: %w", op, err)
-			}
-			images = append(images, extImg.Url)
-		}
-
-		err = rows.Close()
+	if version != nil {
+		err = r.checkVersionContext(ctx, tx, task.Id, *version)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return fmt.Errorf("%s: %w", op, err)
 		}
 	}
-	err = rows.Err()
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
 
-	err = tx.Commit()
+	err = r.workflow.Validate(previousProgressStatus, task.ProgressStatus)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	for i := range tasks {
-		tasks[i].ExternalImages = images
+	err = r.checkParentContext(ctx, tx, task.Id, task.ParentId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return tasks, nil
-}
-
-func (r *Repository) UpdateTaskContext(ctx context.Context, task *model.Task) error {
-	const op = "repository.UpdateTask"
-
-	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	newRank, err := r.rankForNewParent(ctx, tx, task.Id, task.OwnerId, task.ParentId)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
 
-	_, err = r.pgsq.Update("task").
+	query := r.pgsq.Update("task").
 		Set("header", task.Header).
 		Set("text", task.Text).
 		Set("deadline", task.Deadline).
@@ -410,7 +316,15 @@ func (r *Repository) UpdateTaskContext(ctx context.Context, task *model.Task) er
 		Set("owner_id", task.OwnerId).
 		Set("parent_id", task.ParentId).
 		Set("possible_deadline", task.PossibleDeadline).
-		Set("weight", task.Weight).
+		Set("weight", task.Weight)
+
+	if newRank != "" {
+		query = query.Set("rank", newRank)
+	}
+
+	query = setProgressStatusTimestamps(query, previousProgressStatus, task.ProgressStatus, time.Now().UTC())
+
+	_, err = query.
 		Where(sq.Eq{"id": task.Id}).
 		RunWith(tx).
 		ExecContext(ctx)
@@ -437,6 +351,19 @@ func (r *Repository) UpdateTaskContext(ctx context.Context, task *model.Task) er
 		}
 	}
 
+	// nil tags keep the current ones
+	if task.Tags != nil {
+		tagIds := make([]uuid.UUID, 0, len(task.Tags))
+		for _, tag := range task.Tags {
+			tagIds = append(tagIds, tag.Id)
+		}
+
+		err = r.setTaskTags(ctx, tx, task.Id, tagIds)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -470,7 +397,15 @@ func (r *Repository) PatchTaskContext(
 		_ = tx.Rollback()
 	}()
 
-	query := r.pgsq.Update("task")
+	previousProgressStatus, err := r.lockProgressStatus(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// modified_at is always set, so a patch that only replaces images still
+	// has a SET clause
+	query := r.pgsq.Update("task").
+		Set("modified_at", time.Now().UTC())
 
 	if header != nil {
 		query = query.Set("header", header)
@@ -485,7 +420,13 @@ func (r *Repository) PatchTaskContext(
 	}
 
 	if progressStatusPtr != nil {
+		err = r.workflow.Validate(previousProgressStatus, *progressStatusPtr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
 		query = query.Set("progress_status", *progressStatusPtr)
+		query = setProgressStatusTimestamps(query, previousProgressStatus, *progressStatusPtr, time.Now().UTC())
 	}
 
 	if isUrgent != nil {
@@ -501,7 +442,25 @@ func (r *Repository) PatchTaskContext(
 	}
 
 	if parentId != uuid.Nil {
+		err = r.checkParentContext(ctx, tx, id, &parentId)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
 		query = query.Set("parent_id", parentId)
+
+		var newOwnerId int32
+		if ownerId != nil {
+			newOwnerId = *ownerId
+		}
+
+		newRank, err := r.rankForNewParent(ctx, tx, id, newOwnerId, &parentId)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if newRank != "" {
+			query = query.Set("rank", newRank)
+		}
 	}
 
 	if (possibleDeadline != time.Time{}) {
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// lockProgressStatus returns the stored progress status of the task and locks
+// its row until the end of the transaction. Soft-deleted tasks are not found.
+func (r *Repository) lockProgressStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID) (model.ProgressStatus, error) {
+	var status string
+
+	err := r.pgsq.Select("progress_status").
+		From("task").
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"deleted_at": nil}).
+		Suffix("FOR UPDATE").
+		RunWith(tx).
+		QueryRowContext(ctx).
+		Scan(&status)
+	if err != nil {
+		return "", err
+	}
+
+	return model.ProgressStatusFromString(status)
+}
+
+// setProgressStatusTimestamps records when the task was completed or canceled
+// and forgets it once the task leaves that status.
+func setProgressStatusTimestamps(
+	query sq.UpdateBuilder,
+	from model.ProgressStatus,
+	to model.ProgressStatus,
+	now time.Time,
+) sq.UpdateBuilder {
+	if from == to {
+		return query
+	}
+
+	switch {
+	case to == model.ProgressStatusDone:
+		query = query.Set("completed_at", now)
+	case from == model.ProgressStatusDone:
+		query = query.Set("completed_at", nil)
+	}
+
+	switch {
+	case to == model.ProgressStatusCanceled:
+		query = query.Set("canceled_at", now)
+	case from == model.ProgressStatusCanceled:
+		query = query.Set("canceled_at", nil)
+	}
+
+	return query
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+var ErrParentCycle = errors.New("task cannot be moved under itself or its descendant")
+
+// subtreeIdsContext returns the ids of the task and all its descendants.
+func (r *Repository) subtreeIdsContext(ctx context.Context, tx *sql.Tx, rootId uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.pgsq.Select("tree.id").
+		PrefixExpr(subtreesOf([]uuid.UUID{rootId})).
+		From("tree").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// checkParentContext fails with ErrParentCycle when parentId is the task
+// itself or one of its descendants. A nil parentId is always allowed.
+func (r *Repository) checkParentContext(ctx context.Context, tx *sql.Tx, id uuid.UUID, parentId *uuid.UUID) error {
+	if parentId == nil {
+		return nil
+	}
+
+	subtree, err := r.subtreeIdsContext(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, descendantId := range subtree {
+		if descendantId == *parentId {
+			return ErrParentCycle
+		}
+	}
+
+	return nil
+}
+
+// subtreesOf is a "WITH RECURSIVE tree (root_id, id)" prefix pairing each of
+// the given tasks with itself and all its descendants. Soft-deleted tasks are
+// left out. UNION instead of UNION ALL keeps a broken parent_id cycle from
+// recursing forever.
+func subtreesOf(taskIds []uuid.UUID) sq.Sqlizer {
+	return sq.Expr(
+		"WITH RECURSIVE tree (root_id, id) AS (? "+
+			"UNION SELECT tree.root_id, task.id FROM task JOIN tree ON task.parent_id = tree.id "+
+			"WHERE task.deleted_at IS NULL)",
+		sq.Select("task.id", "task.id").
+			From("task").
+			Where(sq.Eq{"task.id": taskIds}).
+			Where(sq.Eq{"task.deleted_at": nil}),
+	)
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+var ErrTagNotFound = errors.New("tag not found for the task owner")
+
+// setTaskTags replaces the tags of the task. Every tag must belong to the
+// owner of the task.
+func (r *Repository) setTaskTags(ctx context.Context, tx *sql.Tx, taskId uuid.UUID, tagIds []uuid.UUID) error {
+	_, err := r.pgsq.Delete("task_tag").
+		Where(sq.Eq{"task_id": taskId}).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(tagIds) == 0 {
+		return nil
+	}
+
+	unique := make(map[uuid.UUID]struct{}, len(tagIds))
+	for _, id := range tagIds {
+		unique[id] = struct{}{}
+	}
+
+	result, err := r.pgsq.Insert("task_tag").
+		Columns("task_id", "tag_id").
+		Select(sq.Select().
+			Column(sq.Expr("?::uuid", taskId)).
+			Column("tag.id").
+			From("tag").
+			Join("task ON task.owner_id = tag.owner_id").
+			Where(sq.Eq{"task.id": taskId}).
+			Where(sq.Eq{"tag.id": tagIds})).
+		RunWith(tx).
+		ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if inserted != int64(len(unique)) {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// tagsContext loads the tags of all the given tasks with a single query.
+func (r *Repository) tagsContext(ctx context.Context, tx *sql.Tx, taskIds []uuid.UUID) (map[uuid.UUID][]*model.Tag, error) {
+	tags := make(map[uuid.UUID][]*model.Tag, len(taskIds))
+	if len(taskIds) == 0 {
+		return tags, nil
+	}
+
+	rows, err := r.pgsq.Select("task_tag.task_id", "tag.id", "tag.owner_id", "tag.name", "tag.color").
+		From("task_tag").
+		Join("tag ON tag.id = task_tag.tag_id").
+		Where(sq.Eq{"task_tag.task_id": taskIds}).
+		OrderBy("tag.name").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var taskId uuid.UUID
+		tag := tagTable{}
+		err = rows.Scan(&taskId, &tag.Id, &tag.OwnerId, &tag.Name, &tag.Color)
+		if err != nil {
+			return nil, err
+		}
+		tags[taskId] = append(tags[taskId], tag.toModel())
+	}
+
+	return tags, rows.Err()
+}
+
+func (t *tagTable) toModel() *model.Tag {
+	return &model.Tag{
+		Id:      t.Id,
+		OwnerId: t.OwnerId,
+		Name:    t.Name,
+		Color:   t.Color,
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// ChecklistItem is a lightweight step of a task that does not deserve a subtask.
+type ChecklistItem struct {
+	Id        uuid.UUID
+	TaskId    uuid.UUID
+	Text      string
+	IsChecked bool
+	Rank      string
+}
+
+// CompletionPercent returns how much of a task is complete given how many of
+// its parts (checklist items and subtasks that were not canceled) are done. A
+// task without parts is either complete or not depending on its status.
+func CompletionPercent(status ProgressStatus, done int, total int) int32 {
+	if total == 0 {
+		if status == ProgressStatusDone {
+			return 100
+		}
+		return 0
+	}
+	return int32(done * 100 / total)
+}
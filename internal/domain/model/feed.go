@@ -0,0 +1,38 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeedToken grants an owner's calendar client access to their tasks, within
+// its scope.
+type FeedToken struct {
+	Id        uuid.UUID
+	OwnerId   int32
+	Scope     FeedTokenScope
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type FeedTokenScope string
+
+const (
+	// FeedTokenScopeFeed only reads the ICS feed.
+	FeedTokenScopeFeed FeedTokenScope = "feed"
+	// FeedTokenScopeCalDAV reads and writes tasks over CalDAV.
+	FeedTokenScopeCalDAV FeedTokenScope = "caldav"
+)
+
+func FeedTokenScopeFromString(s string) (FeedTokenScope, error) {
+	switch s {
+	case "feed":
+		return FeedTokenScopeFeed, nil
+	case "caldav":
+		return FeedTokenScopeCalDAV, nil
+	default:
+		return "", fmt.Errorf("unknown feed token scope: %s", s)
+	}
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrNoThroughput = errors.New("no tasks were completed in the forecast window")
+
+// forecastZ is the z-score of the two-sided 80% confidence range.
+const forecastZ = 1.2816
+
+// Forecast is the predicted completion date of remaining work.
+type Forecast struct {
+	RemainingWeight int64
+	// DailyThroughput is the mean weight completed per day.
+	DailyThroughput float64
+	Expected        time.Time
+	// Earliest and Latest bound the 80% confidence range. Latest is zero when
+	// the throughput varies too much to bound it.
+	Earliest time.Time
+	Latest   time.Time
+}
+
+// Throughput is the weight completed on each of a number of consecutive days.
+type Throughput []float64
+
+// Forecast predicts when remaining weight is done when working from from at
+// the pace of t. The confidence range comes from the standard error of the
+// mean daily throughput.
+func (t Throughput) Forecast(from time.Time, remaining int64) (*Forecast, error) {
+	var sum float64
+	for _, w := range t {
+		sum += w
+	}
+	if sum == 0 {
+		return nil, ErrNoThroughput
+	}
+
+	n := float64(len(t))
+	mean := sum / n
+
+	var variance float64
+	for _, w := range t {
+		variance += (w - mean) * (w - mean)
+	}
+	if n > 1 {
+		variance /= n - 1
+	}
+	margin := forecastZ * math.Sqrt(variance/n)
+
+	forecast := &Forecast{
+		RemainingWeight: remaining,
+		DailyThroughput: mean,
+		Expected:        after(from, remaining, mean),
+		Earliest:        after(from, remaining, mean+margin),
+	}
+	if mean > margin {
+		forecast.Latest = after(from, remaining, mean-margin)
+	}
+
+	return forecast, nil
+}
+
+// after returns the end of the day remaining weight is done at rate per day.
+func after(from time.Time, remaining int64, rate float64) time.Time {
+	days := math.Ceil(float64(remaining) / rate)
+	return from.AddDate(0, 0, int(days))
+}
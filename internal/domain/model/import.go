@@ -0,0 +1,59 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ImportConflict tells an import what to do with a task whose id already
+// exists.
+type ImportConflict string
+
+const (
+	ImportConflictSkip      ImportConflict = "skip"      // keep the existing task
+	ImportConflictOverwrite ImportConflict = "overwrite" // replace the existing task
+	ImportConflictDuplicate ImportConflict = "duplicate" // import every task under a new id
+)
+
+func ImportConflictFromString(s string) (ImportConflict, error) {
+	switch c := ImportConflict(s); c {
+	case ImportConflictSkip, ImportConflictOverwrite, ImportConflictDuplicate:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown import conflict strategy: %q", s)
+	}
+}
+
+// ImportTask is a task read from an import source. Key identifies it in the
+// source, e.g. a row number, for error reports. Task.ParentId refers to the Id
+// of another imported task or of an existing task of the owner. A nil Task.Id
+// gets a new id. Task.Tags are matched to the tags of the owner by name and
+// created when missing, nil Tags leave the tags of an overwritten task alone.
+// A non-nil Task.Checklist likewise replaces the checklist, in its order.
+type ImportTask struct {
+	Key  string
+	Task *Task
+}
+
+type ImportError struct {
+	Key     string
+	Message string
+}
+
+func (e *ImportError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// ImportReport tells what an import did or, for a dry run or an import with
+// errors, what it would have done. An import with errors changes nothing.
+type ImportReport struct {
+	DryRun  bool
+	Applied bool
+	Created int
+	Updated int
+	Skipped int
+	Errors  []*ImportError
+	// Ids maps the keys of the imported tasks to the ids they got.
+	Ids map[string]uuid.UUID
+}
@@ -0,0 +1,24 @@
+package model
+
+// Quadrant is a cell of the Eisenhower matrix.
+type Quadrant string
+
+const (
+	QuadrantDo        Quadrant = "do"        // urgent and important
+	QuadrantSchedule  Quadrant = "schedule"  // important, not urgent
+	QuadrantDelegate  Quadrant = "delegate"  // urgent, not important
+	QuadrantEliminate Quadrant = "eliminate" // neither urgent nor important
+)
+
+func QuadrantOf(isUrgent bool, isImportant bool) Quadrant {
+	switch {
+	case isUrgent && isImportant:
+		return QuadrantDo
+	case isImportant:
+		return QuadrantSchedule
+	case isUrgent:
+		return QuadrantDelegate
+	default:
+		return QuadrantEliminate
+	}
+}
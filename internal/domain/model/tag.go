@@ -0,0 +1,13 @@
+package model
+
+import (
+	"github.com/google/uuid"
+)
+
+// Tag is an owner-scoped label. Color is a free-form string such as "#ff0000".
+type Tag struct {
+	Id      uuid.UUID
+	OwnerId int32
+	Name    string
+	Color   string
+}
@@ -19,20 +19,41 @@ type Task struct {
 	PossibleDeadline time.Time
 	ExternalImages   []string
 	Weight           int32
+	CompletedAt      *time.Time
+	CanceledAt       *time.Time
+	Rank             string
+	Tags             []*Tag
+	Checklist        []*ChecklistItem
+	// Completion is the percentage of checked checklist items and done
+	// subtasks, see CompletionPercent.
+	Completion int32
+	// Version grows with every change of the task, its images, tags or
+	// checklist, and ModifiedAt is the time of the latest one.
+	Version    int64
+	ModifiedAt time.Time
 }
 
 type ProgressStatus string
 
 const (
+	ProgressStatusBacklog    ProgressStatus = "backlog"
 	ProgressStatusInProgress ProgressStatus = "in progress"
+	ProgressStatusBlocked    ProgressStatus = "blocked"
+	ProgressStatusOnHold     ProgressStatus = "on hold"
 	ProgressStatusCanceled   ProgressStatus = "canceled"
 	ProgressStatusDone       ProgressStatus = "done"
 )
 
 func ProgressStatusFromString(s string) (ProgressStatus, error) {
 	switch s {
+	case "backlog":
+		return ProgressStatusBacklog, nil
 	case "in progress":
 		return ProgressStatusInProgress, nil
+	case "blocked":
+		return ProgressStatusBlocked, nil
+	case "on hold":
+		return ProgressStatusOnHold, nil
 	case "canceled":
 		return ProgressStatusCanceled, nil
 	case "done":
@@ -0,0 +1,92 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+var ErrTransitionNotAllowed = errors.New("progress status transition is not allowed")
+
+// DefaultTransitions is used when no transitions are configured. Its format is
+// the one Parse accepts. Like the service before workflows, it allows every
+// transition; deployments restrict it with their own rules.
+const DefaultTransitions = "backlog:in progress,blocked,on hold,done,canceled;" +
+	"in progress:backlog,blocked,on hold,done,canceled;" +
+	"blocked:backlog,in progress,on hold,done,canceled;" +
+	"on hold:backlog,in progress,blocked,done,canceled;" +
+	"done:backlog,in progress,blocked,on hold,canceled;" +
+	"canceled:backlog,in progress,blocked,on hold,done"
+
+// Workflow is a state machine over progress statuses. Keeping the current
+// status is always allowed.
+type Workflow struct {
+	transitions map[model.ProgressStatus]map[model.ProgressStatus]struct{}
+}
+
+// Parse builds a workflow from a list of "from:to,to" rules separated by
+// semicolons, e.g. "backlog:in progress;in progress:done,canceled".
+func Parse(s string) (*Workflow, error) {
+	w := &Workflow{
+		transitions: make(map[model.ProgressStatus]map[model.ProgressStatus]struct{}),
+	}
+
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		fromStr, toList, ok := strings.Cut(rule, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed workflow rule: %q", rule)
+		}
+
+		from, err := model.ProgressStatusFromString(strings.TrimSpace(fromStr))
+		if err != nil {
+			return nil, err
+		}
+
+		if w.transitions[from] == nil {
+			w.transitions[from] = make(map[model.ProgressStatus]struct{})
+		}
+
+		for _, toStr := range strings.Split(toList, ",") {
+			to, err := model.ProgressStatusFromString(strings.TrimSpace(toStr))
+			if err != nil {
+				return nil, err
+			}
+			w.transitions[from][to] = struct{}{}
+		}
+	}
+
+	return w, nil
+}
+
+// Default returns the workflow described by DefaultTransitions.
+func Default() *Workflow {
+	w, err := Parse(DefaultTransitions)
+	if err != nil {
+		panic("invalid default workflow: " + err.Error())
+	}
+	return w
+}
+
+func (w *Workflow) CanTransition(from model.ProgressStatus, to model.ProgressStatus) bool {
+	if from == to {
+		return true
+	}
+	_, ok := w.transitions[from][to]
+	return ok
+}
+
+// Validate returns ErrTransitionNotAllowed when the task cannot move from one
+// status to the other.
+func (w *Workflow) Validate(from model.ProgressStatus, to model.ProgressStatus) error {
+	if !w.CanTransition(from, to) {
+		return fmt.Errorf("%w: from %q to %q", ErrTransitionNotAllowed, from, to)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "empty", in: ""},
+		{name: "single rule", in: "backlog:in progress"},
+		{name: "spaces and trailing separator", in: " backlog : in progress , canceled ; "},
+		{name: "default", in: DefaultTransitions},
+		{name: "missing colon", in: "backlog in progress", wantErr: true},
+		{name: "unknown source", in: "todo:done", wantErr: true},
+		{name: "unknown target", in: "backlog:finished", wantErr: true},
+		{name: "empty target", in: "backlog:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	w, err := Parse("backlog:in progress, canceled; in progress:done")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from model.ProgressStatus
+		to   model.ProgressStatus
+		want bool
+	}{
+		{from: model.ProgressStatusBacklog, to: model.ProgressStatusInProgress, want: true},
+		{from: model.ProgressStatusBacklog, to: model.ProgressStatusCanceled, want: true},
+		{from: model.ProgressStatusInProgress, to: model.ProgressStatusDone, want: true},
+		{from: model.ProgressStatusBacklog, to: model.ProgressStatusDone, want: false},
+		{from: model.ProgressStatusDone, to: model.ProgressStatusInProgress, want: false},
+		{from: model.ProgressStatusBlocked, to: model.ProgressStatusInProgress, want: false},
+		{from: model.ProgressStatusBlocked, to: model.ProgressStatusBlocked, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := w.CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+
+		err := w.Validate(tt.from, tt.to)
+		if tt.want && err != nil {
+			t.Errorf("Validate(%q, %q) error = %v", tt.from, tt.to, err)
+		}
+		if !tt.want && !errors.Is(err, ErrTransitionNotAllowed) {
+			t.Errorf("Validate(%q, %q) error = %v, want ErrTransitionNotAllowed", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestDefaultAllowsEveryTransition(t *testing.T) {
+	w := Default()
+
+	statuses := []model.ProgressStatus{
+		model.ProgressStatusBacklog,
+		model.ProgressStatusInProgress,
+		model.ProgressStatusBlocked,
+		model.ProgressStatusOnHold,
+		model.ProgressStatusDone,
+		model.ProgressStatusCanceled,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if !w.CanTransition(from, to) {
+				t.Errorf("CanTransition(%q, %q) = false, want true", from, to)
+			}
+		}
+	}
+}
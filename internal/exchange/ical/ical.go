@@ -0,0 +1,482 @@
+// Package ical converts tasks to and from RFC 5545 calendars of VTODO
+// components.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+const (
+	prodId = "-//pyramidum//tasks//EN"
+
+	utcLayout      = "20060102T150405Z"
+	localLayout    = "20060102T150405"
+	dateOnlyLayout = "20060102"
+
+	// lineLimit is the length in octets lines are folded at.
+	lineLimit = 75
+
+	// extensions keep what the standard properties cannot express
+	propWeight           = "X-PYRAMIDUM-WEIGHT"
+	propProgressStatus   = "X-PYRAMIDUM-STATUS"
+	propPossibleDeadline = "X-PYRAMIDUM-POSSIBLE-DEADLINE"
+)
+
+var ErrNotCalendar = errors.New("input is not an iCalendar stream")
+
+// Encode writes the tasks as a calendar of VTODO components. now is the
+// DTSTAMP of the components.
+func Encode(w io.Writer, tasks []*model.Task, now time.Time) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.line("BEGIN", nil, "VCALENDAR")
+	e.line("VERSION", nil, "2.0")
+	e.line("PRODID", nil, prodId)
+	e.line("CALSCALE", nil, "GREGORIAN")
+	for _, task := range tasks {
+		e.todo(task, now)
+	}
+	e.line("END", nil, "VCALENDAR")
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) todo(task *model.Task, now time.Time) {
+	e.line("BEGIN", nil, "VTODO")
+	e.line("UID", nil, task.Id.String())
+	e.line("DTSTAMP", nil, now.UTC().Format(utcLayout))
+	e.line("SUMMARY", nil, escape(task.Header))
+	if task.Text != "" {
+		e.line("DESCRIPTION", nil, escape(task.Text))
+	}
+	e.line("DUE", nil, task.Deadline.UTC().Format(utcLayout))
+	e.line("STATUS", nil, statusOf(task.ProgressStatus))
+	e.line("PRIORITY", nil, strconv.Itoa(priorityOf(task.IsUrgent, task.IsImportant)))
+	e.line("PERCENT-COMPLETE", nil, strconv.Itoa(int(task.Completion)))
+	if task.CompletedAt != nil {
+		e.line("COMPLETED", nil, task.CompletedAt.UTC().Format(utcLayout))
+	}
+	if task.ParentId != nil && *task.ParentId != uuid.Nil {
+		e.line("RELATED-TO", []string{"RELTYPE=PARENT"}, task.ParentId.String())
+	}
+	for _, url := range task.ExternalImages {
+		e.line("ATTACH", nil, url)
+	}
+	for _, tag := range task.Tags {
+		e.line("CATEGORIES", nil, escape(tag.Name))
+	}
+	e.line(propWeight, nil, strconv.Itoa(int(task.Weight)))
+	e.line(propProgressStatus, nil, escape(string(task.ProgressStatus)))
+	e.line(propPossibleDeadline, nil, task.PossibleDeadline.UTC().Format(utcLayout))
+	e.line("END", nil, "VTODO")
+}
+
+// line writes a content line folded at lineLimit octets without splitting
+// UTF-8 sequences.
+func (e *encoder) line(name string, params []string, value string) {
+	if e.err != nil {
+		return
+	}
+
+	s := name
+	for _, p := range params {
+		s += ";" + p
+	}
+	s += ":" + value
+
+	// continuation lines start with a space that counts towards the limit
+	limit := lineLimit
+	for len(s) > limit {
+		cut := limit
+		for !isRuneStart(s[cut]) {
+			cut--
+		}
+		_, e.err = e.w.WriteString(s[:cut] + "\r\n ")
+		if e.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = lineLimit - 1
+	}
+	_, e.err = e.w.WriteString(s + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// splitList splits a list value at the commas that are not escaped.
+func splitList(s string) []string {
+	values := make([]string, 0)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(values, s[start:])
+}
+
+// statusOf maps a progress status to the closest VTODO STATUS.
+func statusOf(s model.ProgressStatus) string {
+	switch s {
+	case model.ProgressStatusInProgress:
+		return "IN-PROCESS"
+	case model.ProgressStatusDone:
+		return "COMPLETED"
+	case model.ProgressStatusCanceled:
+		return "CANCELLED"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
+
+func progressStatusOf(status string) model.ProgressStatus {
+	switch strings.ToUpper(status) {
+	case "IN-PROCESS":
+		return model.ProgressStatusInProgress
+	case "COMPLETED":
+		return model.ProgressStatusDone
+	case "CANCELLED":
+		return model.ProgressStatusCanceled
+	default:
+		return model.ProgressStatusBacklog
+	}
+}
+
+// priorityOf maps the Eisenhower quadrant to a PRIORITY, 1 being the highest.
+func priorityOf(isUrgent bool, isImportant bool) int {
+	switch model.QuadrantOf(isUrgent, isImportant) {
+	case model.QuadrantDo:
+		return 1
+	case model.QuadrantSchedule:
+		return 3
+	case model.QuadrantDelegate:
+		return 5
+	default:
+		return 9
+	}
+}
+
+func flagsOf(priority int) (isUrgent bool, isImportant bool) {
+	switch {
+	case priority >= 1 && priority <= 2:
+		return true, true
+	case priority >= 3 && priority <= 4:
+		return false, true
+	case priority == 5:
+		return true, false
+	default:
+		return false, false
+	}
+}
+
+// Defaults are the values Decode gives a task whose VTODO leaves them out.
+// A zero Deadline makes DUE required, a zero PossibleDeadline falls back to
+// the deadline.
+type Defaults struct {
+	Deadline         time.Time
+	PossibleDeadline time.Time
+	Weight           int32
+	ExternalImages   []string
+}
+
+// Decode reads the VTODO components of a calendar for an import by the owner.
+// UIDs that are not UUIDs are turned into stable name-based UUIDs of the
+// owner, so importing the same calendar again finds the same tasks.
+// Components without DUE, X-PYRAMIDUM-POSSIBLE-DEADLINE, X-PYRAMIDUM-WEIGHT or
+// ATTACH get the values of defaults. Floating times are read in loc.
+// Components that cannot be read are reported by UID and left out.
+func Decode(r io.Reader, ownerId int32, defaults Defaults, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, nil, ErrNotCalendar
+	}
+
+	tasks := make([]*model.ImportTask, 0)
+	todoErrors := make([]*model.ImportError, 0)
+
+	var todo []property
+	depth := 0
+	for _, l := range lines {
+		p, err := parseLine(l)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VTODO"):
+			todo = make([]property, 0)
+			depth = 1
+		case todo != nil && p.name == "BEGIN":
+			depth++
+		case todo != nil && p.name == "END":
+			depth--
+			if depth == 0 {
+				key, task, err := decodeTodo(todo, ownerId, defaults, loc)
+				if err != nil {
+					todoErrors = append(todoErrors, &model.ImportError{Key: key, Message: err.Error()})
+				} else {
+					tasks = append(tasks, &model.ImportTask{Key: key, Task: task})
+				}
+				todo = nil
+			}
+		case todo != nil && depth == 1:
+			todo = append(todo, p)
+		}
+	}
+
+	return tasks, todoErrors, nil
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func (p property) param(name string) string {
+	return p.params[name]
+}
+
+// unfold joins folded content lines.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if l == "" {
+			continue
+		}
+		if (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into its name, parameters and value. Quoted
+// parameter values may contain ':' and ';'.
+func parseLine(l string) (property, error) {
+	p := property{params: make(map[string]string)}
+
+	quoted := false
+	start := 0
+	var parts []string
+	for i := 0; i < len(l); i++ {
+		switch c := l[i]; {
+		case c == '"':
+			quoted = !quoted
+		case c == ';' && !quoted:
+			parts = append(parts, l[start:i])
+			start = i + 1
+		case c == ':' && !quoted:
+			parts = append(parts, l[start:i])
+			p.value = l[i+1:]
+			p.name = strings.ToUpper(parts[0])
+			for _, param := range parts[1:] {
+				k, v, _ := strings.Cut(param, "=")
+				p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+			return p, nil
+		}
+	}
+
+	return p, fmt.Errorf("malformed content line: %q", l)
+}
+
+func decodeTodo(props []property, ownerId int32, defaults Defaults, loc *time.Location) (string, *model.Task, error) {
+	task := &model.Task{
+		ProgressStatus: model.ProgressStatusBacklog,
+		Tags:           make([]*model.Tag, 0),
+	}
+	key := "VTODO without UID"
+
+	var hasDue, hasPossibleDeadline, hasWeight, hasAttach bool
+	var status, extendedStatus *property
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "UID":
+			key = p.value
+			task.Id = IdOf(ownerId, p.value)
+		case "SUMMARY":
+			task.Header = unescape(p.value)
+		case "DESCRIPTION":
+			task.Text = unescape(p.value)
+		case "DUE":
+			task.Deadline, err = parseTime(p, loc)
+			hasDue = true
+		case "STATUS":
+			status = &p
+		case propProgressStatus:
+			extendedStatus = &p
+		case "PRIORITY":
+			var priority int
+			priority, err = strconv.Atoi(p.value)
+			task.IsUrgent, task.IsImportant = flagsOf(priority)
+		case "COMPLETED":
+			var completedAt time.Time
+			completedAt, err = parseTime(p, loc)
+			task.CompletedAt = &completedAt
+		case "RELATED-TO":
+			if reltype := p.param("RELTYPE"); reltype == "" || strings.EqualFold(reltype, "PARENT") {
+				parentId := IdOf(ownerId, p.value)
+				task.ParentId = &parentId
+			}
+		case "CATEGORIES":
+			for _, name := range splitList(p.value) {
+				if name = strings.TrimSpace(unescape(name)); name != "" {
+					task.Tags = append(task.Tags, &model.Tag{Name: name})
+				}
+			}
+		case "ATTACH":
+			if p.param("VALUE") != "BINARY" {
+				task.ExternalImages = append(task.ExternalImages, p.value)
+			}
+			hasAttach = true
+		case propWeight:
+			var weight int64
+			weight, err = strconv.ParseInt(p.value, 10, 32)
+			task.Weight = int32(weight)
+			hasWeight = true
+		case propPossibleDeadline:
+			task.PossibleDeadline, err = parseTime(p, loc)
+			hasPossibleDeadline = true
+		}
+		if err != nil {
+			return key, nil, fmt.Errorf("%s: %w", p.name, err)
+		}
+	}
+
+	if !hasDue {
+		if defaults.Deadline.IsZero() {
+			return key, nil, errors.New("DUE is required")
+		}
+		task.Deadline = defaults.Deadline.UTC()
+	}
+	if !hasWeight {
+		task.Weight = defaults.Weight
+	}
+	if !hasAttach {
+		task.ExternalImages = defaults.ExternalImages
+	}
+
+	// a client that does not know the extension may change STATUS and keep
+	// the extended status it was given, STATUS wins then
+	if status != nil {
+		task.ProgressStatus = progressStatusOf(status.value)
+	}
+	if extendedStatus != nil {
+		extended, err := model.ProgressStatusFromString(unescape(extendedStatus.value))
+		if err != nil {
+			return key, nil, fmt.Errorf("%s: %w", propProgressStatus, err)
+		}
+		if status == nil || strings.EqualFold(status.value, statusOf(extended)) {
+			task.ProgressStatus = extended
+		}
+	}
+	if !hasPossibleDeadline {
+		task.PossibleDeadline = defaults.PossibleDeadline.UTC()
+		if defaults.PossibleDeadline.IsZero() {
+			task.PossibleDeadline = task.Deadline
+		}
+	}
+
+	return key, task, nil
+}
+
+// IdOf returns the UUID a UID of the owner stands for. UIDs that are not
+// UUIDs are scoped by the owner, so that calendars of different owners using
+// the same UIDs do not collide.
+func IdOf(ownerId int32, uid string) uuid.UUID {
+	id, err := uuid.Parse(uid)
+	if err != nil {
+		return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("ical:%d:%s", ownerId, uid)))
+	}
+	return id
+}
+
+// parseTime reads a DATE or DATE-TIME value in UTC, in its TZID or, for
+// floating times, in loc.
+func parseTime(p property, loc *time.Location) (time.Time, error) {
+	if tzid := p.param("TZID"); tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err == nil {
+			loc = l
+		}
+	}
+
+	var t time.Time
+	var err error
+	switch {
+	case strings.HasSuffix(p.value, "Z"):
+		t, err = time.Parse(utcLayout, p.value)
+	case len(p.value) == len(dateOnlyLayout):
+		t, err = time.ParseInLocation(dateOnlyLayout, p.value, loc)
+	default:
+		t, err = time.ParseInLocation(localLayout, p.value, loc)
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
@@ -0,0 +1,239 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+var now = time.Date(2024, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+func TestRoundTrip(t *testing.T) {
+	parentId := uuid.New()
+	completedAt := now.Add(-time.Hour)
+	task := &model.Task{
+		Id:               uuid.New(),
+		Header:           "Call; back, " + strings.Repeat("очень длинный заголовок ", 5),
+		Text:             "first line\nsecond \\ line",
+		Deadline:         now.AddDate(0, 0, 3),
+		PossibleDeadline: now.AddDate(0, 0, 2),
+		ProgressStatus:   model.ProgressStatusDone,
+		CompletedAt:      &completedAt,
+		IsUrgent:         true,
+		IsImportant:      true,
+		ParentId:         &parentId,
+		Weight:           5,
+		ExternalImages:   []string{"https://example.com/a.png"},
+		Tags:             []*model.Tag{{Name: "home"}, {Name: "a, b"}},
+	}
+
+	var b bytes.Buffer
+	err := Encode(&b, []*model.Task{task}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(l) > lineLimit {
+			t.Errorf("line of %d octets is not folded: %q", len(l), l)
+		}
+		if strings.HasPrefix(l, " ") && !isRuneStart(l[1]) {
+			t.Errorf("line starts inside a UTF-8 sequence: %q", l)
+		}
+	}
+
+	tasks, todoErrors, err := Decode(&b, 1, Defaults{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todoErrors) != 0 || len(tasks) != 1 {
+		t.Fatalf("Decode() = %d tasks, %v errors, want 1 task", len(tasks), todoErrors)
+	}
+
+	got := tasks[0].Task
+	if got.Id != task.Id || got.Header != task.Header || got.Text != task.Text {
+		t.Errorf("Decode() = %q %q %q, want %q %q %q", got.Id, got.Header, got.Text, task.Id, task.Header, task.Text)
+	}
+	if !got.Deadline.Equal(task.Deadline) || !got.PossibleDeadline.Equal(task.PossibleDeadline) {
+		t.Errorf("Decode() deadlines = %v, %v, want %v, %v", got.Deadline, got.PossibleDeadline, task.Deadline, task.PossibleDeadline)
+	}
+	if got.CompletedAt == nil || !got.CompletedAt.Equal(completedAt) {
+		t.Errorf("Decode() completed at = %v, want %v", got.CompletedAt, completedAt)
+	}
+	if got.ProgressStatus != task.ProgressStatus || got.IsUrgent != task.IsUrgent || got.IsImportant != task.IsImportant {
+		t.Errorf("Decode() = %q, %v, %v, want %q, %v, %v",
+			got.ProgressStatus, got.IsUrgent, got.IsImportant, task.ProgressStatus, task.IsUrgent, task.IsImportant)
+	}
+	if got.ParentId == nil || *got.ParentId != parentId {
+		t.Errorf("Decode() parent = %v, want %v", got.ParentId, parentId)
+	}
+	if got.Weight != task.Weight {
+		t.Errorf("Decode() weight = %d, want %d", got.Weight, task.Weight)
+	}
+	if len(got.ExternalImages) != 1 || got.ExternalImages[0] != task.ExternalImages[0] {
+		t.Errorf("Decode() external images = %v, want %v", got.ExternalImages, task.ExternalImages)
+	}
+	if len(got.Tags) != 2 || got.Tags[0].Name != "home" || got.Tags[1].Name != "a, b" {
+		t.Errorf("Decode() tags = %v, want home and %q", got.Tags, "a, b")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	calendar := func(props ...string) string {
+		lines := append([]string{"BEGIN:VCALENDAR", "BEGIN:VTODO"}, props...)
+		lines = append(lines, "END:VTODO", "END:VCALENDAR")
+		return strings.Join(lines, "\r\n") + "\r\n"
+	}
+	due := time.Date(2024, time.March, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		in       string
+		defaults Defaults
+		check    func(t *testing.T, task *model.Task)
+		wantErr  bool
+	}{
+		{
+			name: "folded lines",
+			in:   calendar("UID:x", "DUE:20240310T090000Z", "SUMMARY:long", "  header"),
+			check: func(t *testing.T, task *model.Task) {
+				if task.Header != "long header" {
+					t.Errorf("header = %q, want %q", task.Header, "long header")
+				}
+			},
+		},
+		{
+			name: "category list",
+			in:   calendar("UID:x", "DUE:20240310T090000Z", `CATEGORIES:work,a\,b`, "CATEGORIES:home"),
+			check: func(t *testing.T, task *model.Task) {
+				names := make([]string, 0, len(task.Tags))
+				for _, tag := range task.Tags {
+					names = append(names, tag.Name)
+				}
+				if strings.Join(names, "|") != "work|a,b|home" {
+					t.Errorf("tags = %q, want work, a,b and home", names)
+				}
+			},
+		},
+		{
+			name: "floating and zoned times",
+			in:   calendar("UID:x", "DUE;TZID=Europe/Berlin:20240310T100000", "COMPLETED:20240309T100000"),
+			check: func(t *testing.T, task *model.Task) {
+				if !task.Deadline.Equal(due) {
+					t.Errorf("deadline = %v, want %v", task.Deadline, due)
+				}
+				if task.CompletedAt == nil || task.CompletedAt.Hour() != 10 {
+					t.Errorf("completed at = %v, want 10:00 UTC", task.CompletedAt)
+				}
+			},
+		},
+		{
+			name: "status wins over a stale extended status",
+			in:   calendar("UID:x", "DUE:20240310T090000Z", "STATUS:COMPLETED", "X-PYRAMIDUM-STATUS:blocked"),
+			check: func(t *testing.T, task *model.Task) {
+				if task.ProgressStatus != model.ProgressStatusDone {
+					t.Errorf("status = %q, want done", task.ProgressStatus)
+				}
+			},
+		},
+		{
+			name: "extended status refines status",
+			in:   calendar("UID:x", "DUE:20240310T090000Z", "STATUS:NEEDS-ACTION", "X-PYRAMIDUM-STATUS:on hold"),
+			check: func(t *testing.T, task *model.Task) {
+				if task.ProgressStatus != model.ProgressStatusOnHold {
+					t.Errorf("status = %q, want on hold", task.ProgressStatus)
+				}
+			},
+		},
+		{
+			name:     "default deadline",
+			in:       calendar("UID:x"),
+			defaults: Defaults{Deadline: due},
+			check: func(t *testing.T, task *model.Task) {
+				if !task.Deadline.Equal(due) || !task.PossibleDeadline.Equal(due) {
+					t.Errorf("deadlines = %v, %v, want %v", task.Deadline, task.PossibleDeadline, due)
+				}
+			},
+		},
+		{
+			name: "defaults for missing extensions",
+			in:   calendar("UID:x", "DUE:20240310T090000Z"),
+			defaults: Defaults{
+				PossibleDeadline: due.Add(-time.Hour),
+				Weight:           5,
+				ExternalImages:   []string{"https://example.com/a.png"},
+			},
+			check: func(t *testing.T, task *model.Task) {
+				if !task.PossibleDeadline.Equal(due.Add(-time.Hour)) || task.Weight != 5 || len(task.ExternalImages) != 1 {
+					t.Errorf("possible deadline, weight, images = %v, %d, %v, want the defaults",
+						task.PossibleDeadline, task.Weight, task.ExternalImages)
+				}
+			},
+		},
+		{
+			name:     "present extensions win over defaults",
+			in:       calendar("UID:x", "DUE:20240310T090000Z", "X-PYRAMIDUM-WEIGHT:0", "ATTACH:https://example.com/b.png"),
+			defaults: Defaults{Weight: 5, ExternalImages: []string{"https://example.com/a.png"}},
+			check: func(t *testing.T, task *model.Task) {
+				if task.Weight != 0 || len(task.ExternalImages) != 1 || task.ExternalImages[0] != "https://example.com/b.png" {
+					t.Errorf("weight, images = %d, %v, want the ones of the VTODO", task.Weight, task.ExternalImages)
+				}
+			},
+		},
+		{
+			name:    "missing due",
+			in:      calendar("UID:x"),
+			wantErr: true,
+		},
+		{
+			name:    "malformed priority",
+			in:      calendar("UID:x", "DUE:20240310T090000Z", "PRIORITY:high"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, todoErrors, err := Decode(strings.NewReader(tt.in), 1, tt.defaults, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr {
+				if len(todoErrors) != 1 || todoErrors[0].Key != "x" {
+					t.Fatalf("Decode() errors = %v, want one for x", todoErrors)
+				}
+				return
+			}
+			if len(todoErrors) != 0 || len(tasks) != 1 {
+				t.Fatalf("Decode() = %d tasks, %v errors, want 1 task", len(tasks), todoErrors)
+			}
+			tt.check(t, tasks[0].Task)
+		})
+	}
+}
+
+func TestDecodeNotCalendar(t *testing.T) {
+	_, _, err := Decode(strings.NewReader("BEGIN:VCARD\r\n"), 1, Defaults{}, time.UTC)
+	if !errors.Is(err, ErrNotCalendar) {
+		t.Errorf("Decode() error = %v, want ErrNotCalendar", err)
+	}
+}
+
+func TestIdOf(t *testing.T) {
+	id := uuid.New()
+	if got := IdOf(1, id.String()); got != id {
+		t.Errorf("IdOf(%q) = %v, want the UUID itself", id, got)
+	}
+
+	if IdOf(1, "todo-1") != IdOf(1, "todo-1") {
+		t.Errorf("IdOf() is not stable")
+	}
+	if IdOf(1, "todo-1") == IdOf(2, "todo-1") {
+		t.Errorf("IdOf() is the same for different owners")
+	}
+}
@@ -0,0 +1,134 @@
+// Package jsondoc is the versioned JSON document tasks are exported to and
+// imported from.
+package jsondoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// Version is the version of the documents New produces.
+const Version = 1
+
+var ErrUnsupportedVersion = errors.New("unsupported document version")
+
+type Document struct {
+	Version    int       `json:"version"`
+	OwnerId    int32     `json:"owner_id"`
+	ExportedAt time.Time `json:"exported_at"`
+	Tasks      []*Task   `json:"tasks"`
+}
+
+// Task is a task of a document. ParentId refers to another task of the document.
+type Task struct {
+	Id               uuid.UUID  `json:"id"`
+	ParentId         *uuid.UUID `json:"parent_id,omitempty"`
+	Header           string     `json:"header"`
+	Text             string     `json:"text"`
+	Deadline         time.Time  `json:"deadline"`
+	PossibleDeadline time.Time  `json:"possible_deadline"`
+	ProgressStatus   string     `json:"progress_status"`
+	IsUrgent         bool       `json:"is_urgent"`
+	IsImportant      bool       `json:"is_important"`
+	Weight           int32      `json:"weight"`
+	ExternalImages   []string   `json:"external_images,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	CanceledAt       *time.Time `json:"canceled_at,omitempty"`
+}
+
+// New builds a document of the tasks of the owner.
+func New(ownerId int32, tasks []*model.Task, now time.Time) *Document {
+	doc := &Document{
+		Version:    Version,
+		OwnerId:    ownerId,
+		ExportedAt: now,
+		Tasks:      make([]*Task, 0, len(tasks)),
+	}
+
+	for _, task := range tasks {
+		doc.Tasks = append(doc.Tasks, &Task{
+			Id:               task.Id,
+			ParentId:         task.ParentId,
+			Header:           task.Header,
+			Text:             task.Text,
+			Deadline:         task.Deadline,
+			PossibleDeadline: task.PossibleDeadline,
+			ProgressStatus:   string(task.ProgressStatus),
+			IsUrgent:         task.IsUrgent,
+			IsImportant:      task.IsImportant,
+			Weight:           task.Weight,
+			ExternalImages:   task.ExternalImages,
+			CompletedAt:      task.CompletedAt,
+			CanceledAt:       task.CanceledAt,
+		})
+	}
+
+	return doc
+}
+
+func Encode(w io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+func Decode(r io.Reader) (*Document, error) {
+	doc := &Document{}
+
+	err := json.NewDecoder(r).Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Version != Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, doc.Version)
+	}
+
+	return doc, nil
+}
+
+// ImportTasks converts the tasks of the document for an import. They are
+// keyed by their id, or by their index when they have none.
+func (d *Document) ImportTasks() ([]*model.ImportTask, error) {
+	tasks := make([]*model.ImportTask, 0, len(d.Tasks))
+
+	for i, t := range d.Tasks {
+		key := "tasks[" + strconv.Itoa(i) + "]"
+		if t.Id != uuid.Nil {
+			key = t.Id.String()
+		}
+
+		progressStatus, err := model.ProgressStatusFromString(t.ProgressStatus)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		tasks = append(tasks, &model.ImportTask{
+			Key: key,
+			Task: &model.Task{
+				Id:               t.Id,
+				ParentId:         t.ParentId,
+				Header:           t.Header,
+				Text:             t.Text,
+				Deadline:         t.Deadline,
+				PossibleDeadline: t.PossibleDeadline,
+				ProgressStatus:   progressStatus,
+				IsUrgent:         t.IsUrgent,
+				IsImportant:      t.IsImportant,
+				Weight:           t.Weight,
+				ExternalImages:   t.ExternalImages,
+				CompletedAt:      t.CompletedAt,
+				CanceledAt:       t.CanceledAt,
+			},
+		})
+	}
+
+	return tasks, nil
+}
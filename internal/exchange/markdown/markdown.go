@@ -0,0 +1,272 @@
+// Package markdown converts tasks to and from nested Markdown checklists.
+//
+// Every task is a "- [ ]" item nested under its parent's item. The box is
+// "x" for done tasks, "-" for canceled and "/" for tasks in progress, and a
+// due: word holds the deadline. The text of a task follows its item as an
+// indented paragraph.
+package markdown
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	keyDue = "due:"
+
+	// indentWidth is how far a child item is indented under its parent.
+	indentWidth = 2
+	// tabWidth is how many spaces a tab indents by.
+	tabWidth = 4
+)
+
+// Write writes the tasks as a checklist, each under its parent when that is
+// written too, siblings in the given order. Dates are written in loc.
+func Write(w io.Writer, tasks []*model.Task, loc *time.Location) error {
+	bw := bufio.NewWriter(w)
+
+	written := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		written[task.Id] = true
+	}
+
+	roots := make([]*model.Task, 0)
+	children := make(map[uuid.UUID][]*model.Task, len(tasks))
+	for _, task := range tasks {
+		if task.ParentId != nil && written[*task.ParentId] {
+			children[*task.ParentId] = append(children[*task.ParentId], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	var write func(task *model.Task, depth int) error
+	write = func(task *model.Task, depth int) error {
+		indent := strings.Repeat(" ", depth*indentWidth)
+
+		item := fmt.Sprintf(
+			"%s- [%c] %s %s%s\n",
+			indent,
+			boxOf(task.ProgressStatus),
+			strings.Join(strings.Fields(task.Header), " "),
+			keyDue,
+			task.Deadline.In(loc).Format(dateLayout),
+		)
+		_, err := bw.WriteString(item)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range strings.Split(strings.TrimSpace(task.Text), "\n") {
+			if l = strings.TrimSpace(l); l == "" {
+				continue
+			}
+			_, err = bw.WriteString(indent + strings.Repeat(" ", indentWidth) + l + "\n")
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, child := range children[task.Id] {
+			err = write(child, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, root := range roots {
+		err := write(root, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Read reads the checklist items for an import, each under the closest item
+// above it that is indented less. Items are keyed by their line. Tasks
+// without due: get defaultDeadline, or are reported when it is zero. Dates are
+// read in loc. Lines that are not part of an item are ignored, and items that
+// cannot be read are reported with their children and left out.
+func Read(r io.Reader, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	tasks := make([]*model.ImportTask, 0)
+	itemErrors := make([]*model.ImportError, 0)
+
+	type open struct {
+		indent int
+		task   *model.Task
+	}
+	// stack holds the items the next one may be nested in, innermost last;
+	// a nil task stands for an item that could not be read
+	stack := make([]open, 0)
+
+	n := 0
+	for scanner.Scan() {
+		n++
+		l := scanner.Text()
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		indent := indentOf(l)
+		box, rest, isItem := parseItem(strings.TrimSpace(l))
+		if !isItem {
+			// text of the last item when indented under it
+			if len(stack) != 0 && indent > stack[len(stack)-1].indent && stack[len(stack)-1].task != nil {
+				last := stack[len(stack)-1].task
+				if last.Text != "" {
+					last.Text += "\n"
+				}
+				last.Text += strings.TrimSpace(l)
+			}
+			continue
+		}
+
+		for len(stack) != 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		key := fmt.Sprintf("line %d", n)
+		task, err := parseTask(box, rest, defaultDeadline, loc)
+		if err == nil && len(stack) != 0 {
+			parent := stack[len(stack)-1].task
+			if parent == nil {
+				err = errors.New("parent item cannot be imported")
+			} else {
+				task.ParentId = &parent.Id
+			}
+		}
+		if err != nil {
+			itemErrors = append(itemErrors, &model.ImportError{Key: key, Message: err.Error()})
+			stack = append(stack, open{indent: indent})
+			continue
+		}
+
+		tasks = append(tasks, &model.ImportTask{Key: key, Task: task})
+		stack = append(stack, open{indent: indent, task: task})
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tasks, itemErrors, nil
+}
+
+// parseItem splits a trimmed "- [ ] header" line into its box and the rest.
+func parseItem(l string) (byte, string, bool) {
+	if len(l) < 6 || !strings.ContainsRune("-*+", rune(l[0])) || l[1] != ' ' {
+		return 0, "", false
+	}
+	l = strings.TrimLeft(l[1:], " ")
+	if len(l) < 3 || l[0] != '[' || l[2] != ']' {
+		return 0, "", false
+	}
+	if len(l) > 3 && l[3] != ' ' {
+		return 0, "", false
+	}
+
+	return l[1], strings.TrimSpace(l[3:]), true
+}
+
+func parseTask(box byte, rest string, defaultDeadline time.Time, loc *time.Location) (*model.Task, error) {
+	progressStatus, ok := progressStatusOf(box)
+	if !ok {
+		return nil, fmt.Errorf("unknown checkbox [%c]", box)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	task := &model.Task{
+		Id:             id,
+		ProgressStatus: progressStatus,
+	}
+
+	header := make([]string, 0)
+	hasDue := false
+	for _, word := range strings.Fields(rest) {
+		if v, isDue := strings.CutPrefix(word, keyDue); isDue && v != "" {
+			task.Deadline, err = time.ParseInLocation(dateLayout, v, loc)
+			if err != nil {
+				return nil, fmt.Errorf("due: %w", err)
+			}
+			hasDue = true
+			continue
+		}
+		header = append(header, word)
+	}
+	task.Header = strings.Join(header, " ")
+
+	if !hasDue {
+		if defaultDeadline.IsZero() {
+			return nil, errors.New("due is required")
+		}
+		task.Deadline = defaultDeadline
+	}
+	task.Deadline = task.Deadline.UTC()
+	task.PossibleDeadline = task.Deadline
+
+	return task, nil
+}
+
+// indentOf measures the leading whitespace of a line in spaces.
+func indentOf(l string) int {
+	indent := 0
+	for _, c := range l {
+		switch c {
+		case ' ':
+			indent++
+		case '\t':
+			indent += tabWidth
+		default:
+			return indent
+		}
+	}
+	return indent
+}
+
+func boxOf(s model.ProgressStatus) byte {
+	switch s {
+	case model.ProgressStatusDone:
+		return 'x'
+	case model.ProgressStatusCanceled:
+		return '-'
+	case model.ProgressStatusInProgress:
+		return '/'
+	default:
+		return ' '
+	}
+}
+
+func progressStatusOf(box byte) (model.ProgressStatus, bool) {
+	switch box {
+	case ' ':
+		return model.ProgressStatusBacklog, true
+	case 'x', 'X':
+		return model.ProgressStatusDone, true
+	case '-':
+		return model.ProgressStatusCanceled, true
+	case '/':
+		return model.ProgressStatusInProgress, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,169 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestReadNesting(t *testing.T) {
+	in := "# Moving\n" +
+		"- [ ] Pack due:2024-03-10\n" +
+		"  boxes from the basement\n" +
+		"  - [x] Kitchen due:2024-03-05\n" +
+		"  - [/] Books due:2024-03-06\n" +
+		"\t- [-] Old magazines due:2024-03-06\n" +
+		"  - [?] Garage due:2024-03-07\n" +
+		"    - [ ] Tools due:2024-03-07\n" +
+		"* [ ] Move due:2024-03-15\n" +
+		"+ [ ] Unpack\n" +
+		"- [] not an item\n"
+
+	tasks, itemErrors, err := Read(strings.NewReader(in), time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// header, status and the header of the parent of every read task
+	type item struct {
+		header string
+		status model.ProgressStatus
+		parent string
+	}
+	want := []item{
+		{header: "Pack", status: model.ProgressStatusBacklog},
+		{header: "Kitchen", status: model.ProgressStatusDone, parent: "Pack"},
+		{header: "Books", status: model.ProgressStatusInProgress, parent: "Pack"},
+		{header: "Old magazines", status: model.ProgressStatusCanceled, parent: "Books"},
+		{header: "Move", status: model.ProgressStatusBacklog},
+	}
+
+	headers := make(map[uuid.UUID]string, len(tasks))
+	for _, imported := range tasks {
+		headers[imported.Task.Id] = imported.Task.Header
+	}
+	if len(tasks) != len(want) {
+		t.Fatalf("Read() = %d tasks, want %d", len(tasks), len(want))
+	}
+	for i, w := range want {
+		task := tasks[i].Task
+		parent := ""
+		if task.ParentId != nil {
+			parent = headers[*task.ParentId]
+		}
+		got := item{header: task.Header, status: task.ProgressStatus, parent: parent}
+		if got != w {
+			t.Errorf("Read()[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	if tasks[0].Task.Text != "boxes from the basement" {
+		t.Errorf("Pack text = %q, want the paragraph under it", tasks[0].Task.Text)
+	}
+
+	// the unknown box, its child and the item without due are reported
+	keys := make([]string, 0, len(itemErrors))
+	for _, e := range itemErrors {
+		keys = append(keys, e.Key)
+	}
+	if strings.Join(keys, ", ") != "line 7, line 8, line 10" {
+		t.Errorf("Read() errors for %q, want lines 7, 8 and 10", keys)
+	}
+}
+
+func TestReadDefaultDeadline(t *testing.T) {
+	defaultDeadline := date(2024, time.April, 1)
+
+	tasks, itemErrors, err := Read(strings.NewReader("- [ ] Someday\n"), defaultDeadline, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itemErrors) != 0 || len(tasks) != 1 {
+		t.Fatalf("Read() = %d tasks, %v errors, want 1 task", len(tasks), itemErrors)
+	}
+	if !tasks[0].Task.Deadline.Equal(defaultDeadline) {
+		t.Errorf("deadline = %v, want %v", tasks[0].Task.Deadline, defaultDeadline)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	root := &model.Task{
+		Id:             uuid.New(),
+		Header:         "Trip",
+		Text:           "passport\ntickets",
+		Deadline:       date(2024, time.May, 1),
+		ProgressStatus: model.ProgressStatusInProgress,
+	}
+	child := &model.Task{
+		Id:             uuid.New(),
+		ParentId:       &root.Id,
+		Header:         "Book  a hotel",
+		Deadline:       date(2024, time.April, 1),
+		ProgressStatus: model.ProgressStatusDone,
+	}
+	grandchild := &model.Task{
+		Id:             uuid.New(),
+		ParentId:       &child.Id,
+		Header:         "Compare prices",
+		Deadline:       date(2024, time.March, 20),
+		ProgressStatus: model.ProgressStatusCanceled,
+	}
+	other := &model.Task{
+		Id:             uuid.New(),
+		Header:         "Water plants",
+		Deadline:       date(2024, time.March, 15),
+		ProgressStatus: model.ProgressStatusBlocked,
+	}
+
+	var b bytes.Buffer
+	err := Write(&b, []*model.Task{grandchild, root, other, child}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, itemErrors, err := Read(&b, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(itemErrors) != 0 || len(tasks) != 4 {
+		t.Fatalf("Read() = %d tasks, %v errors, want 4 tasks", len(tasks), itemErrors)
+	}
+
+	// parents are written before their subtasks, and statuses without a box
+	// of their own are read as backlog
+	want := []*model.Task{root, child, grandchild, other}
+	wantStatuses := []model.ProgressStatus{
+		model.ProgressStatusInProgress,
+		model.ProgressStatusDone,
+		model.ProgressStatusCanceled,
+		model.ProgressStatusBacklog,
+	}
+	for i, w := range want {
+		got := tasks[i].Task
+		if got.Header != strings.Join(strings.Fields(w.Header), " ") || !got.Deadline.Equal(w.Deadline) {
+			t.Errorf("Read()[%d] = %q %v, want %q %v", i, got.Header, got.Deadline, w.Header, w.Deadline)
+		}
+		if got.ProgressStatus != wantStatuses[i] {
+			t.Errorf("Read()[%d] status = %q, want %q", i, got.ProgressStatus, wantStatuses[i])
+		}
+	}
+	if tasks[0].Task.Text != root.Text {
+		t.Errorf("root text = %q, want %q", tasks[0].Task.Text, root.Text)
+	}
+	if tasks[1].Task.ParentId == nil || *tasks[1].Task.ParentId != tasks[0].Task.Id {
+		t.Errorf("child is not under the root")
+	}
+	if tasks[2].Task.ParentId == nil || *tasks[2].Task.ParentId != tasks[1].Task.Id {
+		t.Errorf("grandchild is not under the child")
+	}
+	if tasks[3].Task.ParentId != nil {
+		t.Errorf("other task has parent %v, want none", tasks[3].Task.ParentId)
+	}
+}
@@ -0,0 +1,327 @@
+// Package taskcsv converts tasks to and from CSV files, one task per row.
+package taskcsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+// Field is a task field a CSV column can be mapped to.
+type Field string
+
+const (
+	FieldKey              Field = "key"
+	FieldParentKey        Field = "parent_key"
+	FieldHeader           Field = "header"
+	FieldText             Field = "text"
+	FieldDeadline         Field = "deadline"
+	FieldPossibleDeadline Field = "possible_deadline"
+	FieldProgressStatus   Field = "progress_status"
+	FieldIsUrgent         Field = "is_urgent"
+	FieldIsImportant      Field = "is_important"
+	FieldWeight           Field = "weight"
+	FieldExternalImages   Field = "external_images"
+)
+
+// Fields are the fields in the order Write puts them.
+var Fields = []Field{
+	FieldKey,
+	FieldParentKey,
+	FieldHeader,
+	FieldText,
+	FieldDeadline,
+	FieldPossibleDeadline,
+	FieldProgressStatus,
+	FieldIsUrgent,
+	FieldIsImportant,
+	FieldWeight,
+	FieldExternalImages,
+}
+
+// dateLayouts are tried in order when reading dates. Layouts without a zone
+// are read in the location of the Reader.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+var ErrMissingColumn = errors.New("missing column")
+
+// Mapping maps task fields to the names of the CSV columns holding them.
+// Unmapped fields take their defaults.
+type Mapping map[Field]string
+
+// DefaultMapping maps every field to the column of the same name.
+func DefaultMapping() Mapping {
+	m := make(Mapping, len(Fields))
+	for _, f := range Fields {
+		m[f] = string(f)
+	}
+	return m
+}
+
+// ParseMapping reads a mapping like "header=Title,deadline=Due date". Fields
+// that are not mentioned keep their default column.
+func ParseMapping(s string) (Mapping, error) {
+	m := DefaultMapping()
+	if strings.TrimSpace(s) == "" {
+		return m, nil
+	}
+
+	known := make(map[Field]struct{}, len(Fields))
+	for _, f := range Fields {
+		known[f] = struct{}{}
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed column mapping: %q", pair)
+		}
+		f := Field(strings.TrimSpace(field))
+		if _, ok := known[f]; !ok {
+			return nil, fmt.Errorf("unknown task field: %q", field)
+		}
+		m[f] = strings.TrimSpace(column)
+	}
+
+	return m, nil
+}
+
+// Write writes the tasks with a header row of Fields. Tasks are keyed by their
+// id and dates are written in loc.
+func Write(w io.Writer, tasks []*model.Task, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+
+	header := make([]string, 0, len(Fields))
+	for _, f := range Fields {
+		header = append(header, string(f))
+	}
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		parentKey := ""
+		if task.ParentId != nil && *task.ParentId != uuid.Nil {
+			parentKey = task.ParentId.String()
+		}
+
+		err = cw.Write([]string{
+			task.Id.String(),
+			parentKey,
+			task.Header,
+			task.Text,
+			task.Deadline.In(loc).Format(time.RFC3339),
+			task.PossibleDeadline.In(loc).Format(time.RFC3339),
+			string(task.ProgressStatus),
+			strconv.FormatBool(task.IsUrgent),
+			strconv.FormatBool(task.IsImportant),
+			strconv.FormatInt(int64(task.Weight), 10),
+			strings.Join(task.ExternalImages, " "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Read reads tasks for an import. Rows are keyed by their key column, or by
+// their line when it is empty. A row refers to its parent by the parent's key,
+// or by the id of an existing task. Rows that cannot be read are reported
+// with their line and left out.
+func Read(r io.Reader, mapping Mapping, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make(map[Field]int, len(mapping))
+	for f, name := range mapping {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				columns[f] = i
+				break
+			}
+		}
+	}
+	for _, f := range []Field{FieldHeader, FieldDeadline} {
+		if _, ok := columns[f]; !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMissingColumn, mapping[f])
+		}
+	}
+
+	rows := make([]*row, 0)
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, &row{line: line, record: record, columns: columns})
+	}
+
+	// every row gets its id before parents are resolved, so that a child may
+	// come before its parent
+	ids := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		key := row.get(FieldKey)
+		if key == "" {
+			continue
+		}
+		id, err := uuid.Parse(key)
+		if err != nil {
+			id, err = uuid.NewRandom()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		ids[key] = id
+	}
+
+	tasks := make([]*model.ImportTask, 0, len(rows))
+	rowErrors := make([]*model.ImportError, 0)
+	for _, row := range rows {
+		task, err := row.task(ids, loc)
+		if err != nil {
+			rowErrors = append(rowErrors, &model.ImportError{Key: row.key(), Message: err.Error()})
+			continue
+		}
+		tasks = append(tasks, &model.ImportTask{Key: row.key(), Task: task})
+	}
+
+	return tasks, rowErrors, nil
+}
+
+type row struct {
+	line    int
+	record  []string
+	columns map[Field]int
+}
+
+func (r *row) get(f Field) string {
+	i, ok := r.columns[f]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r *row) key() string {
+	if key := r.get(FieldKey); key != "" {
+		return fmt.Sprintf("line %d (%s)", r.line, key)
+	}
+	return fmt.Sprintf("line %d", r.line)
+}
+
+func (r *row) task(ids map[string]uuid.UUID, loc *time.Location) (*model.Task, error) {
+	task := &model.Task{
+		Id:             ids[r.get(FieldKey)],
+		Header:         r.get(FieldHeader),
+		Text:           r.get(FieldText),
+		ProgressStatus: model.ProgressStatusBacklog,
+	}
+
+	if parentKey := r.get(FieldParentKey); parentKey != "" {
+		parentId, ok := ids[parentKey]
+		if !ok {
+			var err error
+			parentId, err = uuid.Parse(parentKey)
+			if err != nil {
+				return nil, fmt.Errorf("unknown parent key %q", parentKey)
+			}
+		}
+		task.ParentId = &parentId
+	}
+
+	var err error
+	task.Deadline, err = parseDate(r.get(FieldDeadline), loc)
+	if err != nil {
+		return nil, fmt.Errorf("deadline: %w", err)
+	}
+
+	task.PossibleDeadline = task.Deadline
+	if s := r.get(FieldPossibleDeadline); s != "" {
+		task.PossibleDeadline, err = parseDate(s, loc)
+		if err != nil {
+			return nil, fmt.Errorf("possible deadline: %w", err)
+		}
+	}
+
+	if s := r.get(FieldProgressStatus); s != "" {
+		task.ProgressStatus, err = model.ProgressStatusFromString(strings.ToLower(s))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	task.IsUrgent, err = parseBool(r.get(FieldIsUrgent))
+	if err != nil {
+		return nil, fmt.Errorf("is urgent: %w", err)
+	}
+
+	task.IsImportant, err = parseBool(r.get(FieldIsImportant))
+	if err != nil {
+		return nil, fmt.Errorf("is important: %w", err)
+	}
+
+	if s := r.get(FieldWeight); s != "" {
+		weight, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("weight: %w", err)
+		}
+		task.Weight = int32(weight)
+	}
+
+	task.ExternalImages = strings.Fields(r.get(FieldExternalImages))
+
+	return task, nil
+}
+
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("is required")
+	}
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse date %q", s)
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y", "x":
+		return true, nil
+	default:
+		return false, fmt.Errorf("cannot parse %q as a boolean", s)
+	}
+}
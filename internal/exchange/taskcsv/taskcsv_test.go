@@ -0,0 +1,149 @@
+package taskcsv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Mapping
+		wantErr bool
+	}{
+		{name: "empty", in: " ", want: Mapping{FieldHeader: "header", FieldDeadline: "deadline"}},
+		{
+			name: "renamed columns",
+			in:   "header = Title,deadline=Due date",
+			want: Mapping{FieldHeader: "Title", FieldDeadline: "Due date", FieldWeight: "weight"},
+		},
+		{name: "missing equals sign", in: "header", wantErr: true},
+		{name: "unknown field", in: "title=Title", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMapping(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMapping(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			for f, column := range tt.want {
+				if got[f] != column {
+					t.Errorf("ParseMapping(%q)[%s] = %q, want %q", tt.in, f, got[f], column)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	deadline := time.Date(2024, time.March, 10, 9, 0, 0, 0, time.UTC)
+	parent := &model.Task{
+		Id:               uuid.New(),
+		Header:           "Move, then unpack",
+		Text:             "line one\n\"quoted\" line two",
+		Deadline:         deadline,
+		PossibleDeadline: deadline.Add(-time.Hour),
+		ProgressStatus:   model.ProgressStatusOnHold,
+		IsImportant:      true,
+		Weight:           3,
+		ExternalImages:   []string{"https://example.com/a.png", "https://example.com/b.png"},
+	}
+	child := &model.Task{
+		Id:               uuid.New(),
+		ParentId:         &parent.Id,
+		Header:           "Pack",
+		Deadline:         deadline,
+		PossibleDeadline: deadline,
+		ProgressStatus:   model.ProgressStatusDone,
+		IsUrgent:         true,
+	}
+
+	var b bytes.Buffer
+	err := Write(&b, []*model.Task{child, parent}, time.FixedZone("UTC+3", 3*60*60))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, rowErrors, err := Read(&b, DefaultMapping(), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rowErrors) != 0 || len(tasks) != 2 {
+		t.Fatalf("Read() = %d tasks, %v errors, want 2 tasks", len(tasks), rowErrors)
+	}
+
+	for i, want := range []*model.Task{child, parent} {
+		got := tasks[i].Task
+		if got.Id != want.Id || got.Header != want.Header || got.Text != want.Text {
+			t.Errorf("Read()[%d] = %v %q %q, want %v %q %q", i, got.Id, got.Header, got.Text, want.Id, want.Header, want.Text)
+		}
+		if (got.ParentId == nil) != (want.ParentId == nil) || got.ParentId != nil && *got.ParentId != *want.ParentId {
+			t.Errorf("Read()[%d] parent = %v, want %v", i, got.ParentId, want.ParentId)
+		}
+		if !got.Deadline.Equal(want.Deadline) || !got.PossibleDeadline.Equal(want.PossibleDeadline) {
+			t.Errorf("Read()[%d] deadlines = %v, %v, want %v, %v", i, got.Deadline, got.PossibleDeadline, want.Deadline, want.PossibleDeadline)
+		}
+		if got.ProgressStatus != want.ProgressStatus || got.IsUrgent != want.IsUrgent || got.IsImportant != want.IsImportant {
+			t.Errorf("Read()[%d] = %q, %v, %v, want %q, %v, %v", i,
+				got.ProgressStatus, got.IsUrgent, got.IsImportant, want.ProgressStatus, want.IsUrgent, want.IsImportant)
+		}
+		if got.Weight != want.Weight || strings.Join(got.ExternalImages, " ") != strings.Join(want.ExternalImages, " ") {
+			t.Errorf("Read()[%d] = %d %v, want %d %v", i, got.Weight, got.ExternalImages, want.Weight, want.ExternalImages)
+		}
+	}
+}
+
+func TestReadMapping(t *testing.T) {
+	in := "Title,Due,Parent,Id,Urgent\n" +
+		"Child,2024-03-10,p,,yes\n" +
+		"Parent,10.03.2024 09:30,,p,\n" +
+		"Orphan,2024-03-10,missing,,\n" +
+		"Late,someday,,,\n"
+
+	mapping, err := ParseMapping("header=Title,deadline=Due,parent_key=Parent,key=Id,is_urgent=Urgent")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc := time.FixedZone("UTC+1", 60*60)
+	tasks, rowErrors, err := Read(strings.NewReader(in), mapping, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != 2 {
+		t.Fatalf("Read() = %d tasks, want 2", len(tasks))
+	}
+	child, parent := tasks[0].Task, tasks[1].Task
+	if child.ParentId == nil || *child.ParentId != parent.Id {
+		t.Errorf("child parent = %v, want %v", child.ParentId, parent.Id)
+	}
+	if !child.IsUrgent || parent.IsUrgent {
+		t.Errorf("urgent = %v, %v, want true, false", child.IsUrgent, parent.IsUrgent)
+	}
+	if want := time.Date(2024, time.March, 10, 8, 30, 0, 0, time.UTC); !parent.Deadline.Equal(want) {
+		t.Errorf("parent deadline = %v, want %v", parent.Deadline, want)
+	}
+	if child.ProgressStatus != model.ProgressStatusBacklog {
+		t.Errorf("child status = %q, want backlog", child.ProgressStatus)
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Key != "line 4" || rowErrors[1].Key != "line 5" {
+		t.Errorf("Read() errors = %v, want lines 4 and 5", rowErrors)
+	}
+}
+
+func TestReadMissingColumn(t *testing.T) {
+	_, _, err := Read(strings.NewReader("header,weight\na,1\n"), DefaultMapping(), time.UTC)
+	if !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Read() error = %v, want ErrMissingColumn", err)
+	}
+}
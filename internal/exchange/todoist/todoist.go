@@ -0,0 +1,298 @@
+// Package todoist reads a Todoist JSON backup, as returned by a full sync, for
+// an import.
+//
+// Every open project becomes a task under its parent project, with its items
+// as subtasks nested like in Todoist. Priorities p1 to p3 stand for the
+// Eisenhower quadrants, labels for tags and file attachments of comments for
+// external images. Archived projects and deleted items are left out.
+package todoist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+var ErrNotBackup = errors.New("input is not a Todoist backup")
+
+// dueLayouts are the forms of Todoist due dates: full-day, floating and UTC.
+var dueLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+type backup struct {
+	Projects []project `json:"projects"`
+	Items    []item    `json:"items"`
+	Labels   []struct {
+		Id   id     `json:"id"`
+		Name string `json:"name"`
+	} `json:"labels"`
+	Notes []struct {
+		ItemId         id `json:"item_id"`
+		FileAttachment *struct {
+			FileUrl string `json:"file_url"`
+		} `json:"file_attachment"`
+		IsDeleted bool `json:"is_deleted"`
+	} `json:"notes"`
+}
+
+type project struct {
+	Id         id     `json:"id"`
+	Name       string `json:"name"`
+	ParentId   id     `json:"parent_id"`
+	ChildOrder int    `json:"child_order"`
+	IsArchived bool   `json:"is_archived"`
+	IsDeleted  bool   `json:"is_deleted"`
+}
+
+type item struct {
+	Id          id         `json:"id"`
+	ProjectId   id         `json:"project_id"`
+	ParentId    id         `json:"parent_id"`
+	Content     string     `json:"content"`
+	Description string     `json:"description"`
+	Priority    int        `json:"priority"`
+	ChildOrder  int        `json:"child_order"`
+	Checked     bool       `json:"checked"`
+	IsDeleted   bool       `json:"is_deleted"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Due         *struct {
+		Date     string `json:"date"`
+		Timezone string `json:"timezone"`
+	} `json:"due"`
+	// Labels are names in current backups and label ids in old ones.
+	Labels []id `json:"labels"`
+}
+
+// id is a Todoist id, a string in current backups and a number in old ones.
+type id string
+
+func (i *id) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*i = ""
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*i = id(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("todoist id: %w", err)
+	}
+	*i = id(n.String())
+	return nil
+}
+
+// Read reads a backup for an import by the owner. Projects and items are keyed
+// by their name and get ids derived from the owner and their Todoist ids, so
+// importing the same backup again finds the same tasks. Items without a due date get
+// defaultDeadline, or are reported when it is zero, and a project is due with
+// its last item. Floating due dates are read in loc.
+func Read(r io.Reader, ownerId int32, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	b := backup{}
+	err := json.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.Projects == nil || b.Items == nil {
+		return nil, nil, ErrNotBackup
+	}
+
+	sort.SliceStable(b.Projects, func(i, j int) bool { return b.Projects[i].ChildOrder < b.Projects[j].ChildOrder })
+	sort.SliceStable(b.Items, func(i, j int) bool { return b.Items[i].ChildOrder < b.Items[j].ChildOrder })
+
+	labels := make(map[id]string, len(b.Labels))
+	for _, label := range b.Labels {
+		labels[label.Id] = label.Name
+	}
+
+	attachments := make(map[id][]string)
+	for _, note := range b.Notes {
+		if !note.IsDeleted && note.FileAttachment != nil && note.FileAttachment.FileUrl != "" {
+			attachments[note.ItemId] = append(attachments[note.ItemId], note.FileAttachment.FileUrl)
+		}
+	}
+
+	tasks := make([]*model.ImportTask, 0, len(b.Projects)+len(b.Items))
+	byId := make(map[uuid.UUID]*model.ImportTask, len(b.Projects)+len(b.Items))
+	itemErrors := make([]*model.ImportError, 0)
+
+	// a project is read even when its parent is archived, it goes to the top
+	// level then
+	projects := make(map[id]bool, len(b.Projects))
+	for _, p := range b.Projects {
+		if !p.IsArchived && !p.IsDeleted {
+			projects[p.Id] = true
+		}
+	}
+	for _, p := range b.Projects {
+		if !projects[p.Id] {
+			continue
+		}
+		t := &model.ImportTask{
+			Key: "project " + p.Name,
+			Task: &model.Task{
+				Id:             idOf(ownerId, "project", p.Id),
+				Header:         p.Name,
+				ProgressStatus: model.ProgressStatusBacklog,
+			},
+		}
+		if projects[p.ParentId] {
+			parentId := idOf(ownerId, "project", p.ParentId)
+			t.Task.ParentId = &parentId
+		}
+		tasks = append(tasks, t)
+		byId[t.Task.Id] = t
+	}
+
+	for _, i := range b.Items {
+		if i.IsDeleted || !projects[i.ProjectId] {
+			continue
+		}
+
+		key := "item " + i.Content
+		task, err := i.task(ownerId, labels, attachments[i.Id], defaultDeadline, loc)
+		if err != nil {
+			itemErrors = append(itemErrors, &model.ImportError{Key: key, Message: err.Error()})
+			continue
+		}
+		tasks = append(tasks, &model.ImportTask{Key: key, Task: task})
+		byId[task.Id] = tasks[len(tasks)-1]
+	}
+
+	// every ancestor is due no earlier than its descendants
+	for _, t := range tasks {
+		for parentId := t.Task.ParentId; parentId != nil; {
+			parent, ok := byId[*parentId]
+			if !ok || !t.Task.Deadline.After(parent.Task.Deadline) {
+				break
+			}
+			parent.Task.Deadline = t.Task.Deadline
+			parent.Task.PossibleDeadline = t.Task.Deadline
+			parentId = parent.Task.ParentId
+		}
+	}
+
+	// projects without an item to take a deadline from
+	read := make([]*model.ImportTask, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Task.Deadline.IsZero() {
+			if defaultDeadline.IsZero() {
+				itemErrors = append(itemErrors, &model.ImportError{Key: t.Key, Message: "due date is required"})
+				continue
+			}
+			t.Task.Deadline = defaultDeadline.UTC()
+			t.Task.PossibleDeadline = t.Task.Deadline
+		}
+		read = append(read, t)
+	}
+
+	return read, itemErrors, nil
+}
+
+func (i *item) task(
+	ownerId int32,
+	labels map[id]string,
+	attachments []string,
+	defaultDeadline time.Time,
+	loc *time.Location,
+) (*model.Task, error) {
+	task := &model.Task{
+		Id:             idOf(ownerId, "item", i.Id),
+		Header:         i.Content,
+		Text:           i.Description,
+		ProgressStatus: model.ProgressStatusBacklog,
+		ExternalImages: attachments,
+		Tags:           make([]*model.Tag, 0, len(i.Labels)),
+	}
+	task.IsUrgent, task.IsImportant = flagsOf(i.Priority)
+
+	parentId := idOf(ownerId, "project", i.ProjectId)
+	if i.ParentId != "" {
+		parentId = idOf(ownerId, "item", i.ParentId)
+	}
+	task.ParentId = &parentId
+
+	if i.Checked {
+		task.ProgressStatus = model.ProgressStatusDone
+		if i.CompletedAt != nil {
+			completedAt := i.CompletedAt.UTC()
+			task.CompletedAt = &completedAt
+		}
+	}
+
+	for _, label := range i.Labels {
+		name, ok := labels[label]
+		if !ok {
+			name = string(label)
+		}
+		task.Tags = append(task.Tags, &model.Tag{Name: name})
+	}
+
+	switch {
+	case i.Due != nil && i.Due.Date != "":
+		deadline, err := parseDue(i.Due.Date, i.Due.Timezone, loc)
+		if err != nil {
+			return nil, err
+		}
+		task.Deadline = deadline
+	case !defaultDeadline.IsZero():
+		task.Deadline = defaultDeadline.UTC()
+	default:
+		return nil, errors.New("due date is required")
+	}
+	task.PossibleDeadline = task.Deadline
+
+	return task, nil
+}
+
+// parseDue reads a due date in UTC. Floating ones are in their time zone, or
+// in loc when they have none.
+func parseDue(date string, timezone string, loc *time.Location) (time.Time, error) {
+	if timezone != "" {
+		l, err := time.LoadLocation(timezone)
+		if err == nil {
+			loc = l
+		}
+	}
+
+	for _, layout := range dueLayouts {
+		t, err := time.ParseInLocation(layout, date, loc)
+		if err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("cannot parse due date %q", date)
+}
+
+func idOf(ownerId int32, kind string, todoistId id) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("todoist:%d:%s:%s", ownerId, kind, todoistId)))
+}
+
+// flagsOf maps a Todoist priority, 4 being p1, to the Eisenhower quadrant.
+func flagsOf(priority int) (isUrgent bool, isImportant bool) {
+	switch priority {
+	case 4:
+		return true, true
+	case 3:
+		return false, true
+	case 2:
+		return true, false
+	default:
+		return false, false
+	}
+}
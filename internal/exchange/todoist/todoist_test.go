@@ -0,0 +1,129 @@
+package todoist
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+const export = `{
+	"projects": [
+		{"id": "p2", "name": "Garden", "parent_id": "p1", "child_order": 1},
+		{"id": "p1", "name": "Home", "parent_id": null, "child_order": 0},
+		{"id": "p3", "name": "Old", "child_order": 2, "is_archived": true},
+		{"id": "p4", "name": "Empty", "child_order": 3}
+	],
+	"items": [
+		{"id": "i1", "project_id": "p2", "content": "Mow", "priority": 4, "child_order": 0,
+			"due": {"date": "2024-03-10"}, "labels": ["weekend", 7]},
+		{"id": "i2", "project_id": "p2", "parent_id": "i1", "content": "Fix the mower", "priority": 2,
+			"child_order": 1, "due": {"date": "2024-03-20T10:00:00", "timezone": "Europe/Berlin"}},
+		{"id": 3, "project_id": "p1", "content": "Pay bills", "checked": true, "child_order": 2,
+			"completed_at": "2024-03-01T12:00:00Z", "due": {"date": "2024-03-05T09:00:00Z"}},
+		{"id": "i4", "project_id": "p3", "content": "Archived", "child_order": 3, "due": {"date": "2024-03-01"}},
+		{"id": "i5", "project_id": "p1", "content": "Deleted", "is_deleted": true, "child_order": 4},
+		{"id": "i6", "project_id": "p1", "content": "Someday", "child_order": 5},
+		{"id": "i7", "project_id": "p1", "content": "Broken", "child_order": 6, "due": {"date": "next week"}}
+	],
+	"labels": [{"id": 7, "name": "outdoor"}],
+	"notes": [
+		{"item_id": "i1", "file_attachment": {"file_url": "https://example.com/lawn.png"}},
+		{"item_id": "i1", "file_attachment": {"file_url": "https://example.com/old.png"}, "is_deleted": true}
+	]
+}`
+
+func TestRead(t *testing.T) {
+	tasks, itemErrors, err := Read(strings.NewReader(export), 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byKey := make(map[string]*model.Task, len(tasks))
+	for _, imported := range tasks {
+		byKey[imported.Key] = imported.Task
+	}
+	if len(tasks) != 5 {
+		t.Errorf("Read() = %d tasks, want 5", len(tasks))
+	}
+
+	errorKeys := make([]string, 0, len(itemErrors))
+	for _, e := range itemErrors {
+		errorKeys = append(errorKeys, e.Key)
+	}
+	if strings.Join(errorKeys, ", ") != "item Someday, item Broken, project Empty" {
+		t.Errorf("Read() errors for %q, want Someday, Broken and the empty project", errorKeys)
+	}
+
+	home, garden, mow, fix, bills := byKey["project Home"], byKey["project Garden"], byKey["item Mow"],
+		byKey["item Fix the mower"], byKey["item Pay bills"]
+	if home == nil || garden == nil || mow == nil || fix == nil || bills == nil {
+		t.Fatal("Read() left out open projects or items")
+	}
+
+	if garden.ParentId == nil || *garden.ParentId != home.Id {
+		t.Errorf("subproject parent = %v, want %v", garden.ParentId, home.Id)
+	}
+	if mow.ParentId == nil || *mow.ParentId != garden.Id {
+		t.Errorf("item parent = %v, want its project %v", mow.ParentId, garden.Id)
+	}
+	if fix.ParentId == nil || *fix.ParentId != mow.Id {
+		t.Errorf("subtask parent = %v, want its item %v", fix.ParentId, mow.Id)
+	}
+
+	// floating due dates are in their time zone, ancestors are due with their
+	// last descendant
+	fixDue := time.Date(2024, time.March, 20, 9, 0, 0, 0, time.UTC)
+	if !fix.Deadline.Equal(fixDue) {
+		t.Errorf("subtask deadline = %v, want %v", fix.Deadline, fixDue)
+	}
+	for _, task := range []*model.Task{mow, garden, home} {
+		if !task.Deadline.Equal(fixDue) {
+			t.Errorf("task %q deadline = %v, want %v", task.Header, task.Deadline, fixDue)
+		}
+	}
+
+	if !mow.IsUrgent || !mow.IsImportant || !fix.IsUrgent || fix.IsImportant {
+		t.Errorf("flags are not read from priorities")
+	}
+	if len(mow.Tags) != 2 || mow.Tags[0].Name != "weekend" || mow.Tags[1].Name != "outdoor" {
+		t.Errorf("item tags = %v, want weekend and outdoor", mow.Tags)
+	}
+	if len(mow.ExternalImages) != 1 {
+		t.Errorf("item images = %v, want the one that is not deleted", mow.ExternalImages)
+	}
+	if bills.ProgressStatus != model.ProgressStatusDone || bills.CompletedAt == nil {
+		t.Errorf("checked item = %q, %v, want done with its completion", bills.ProgressStatus, bills.CompletedAt)
+	}
+}
+
+func TestReadOwners(t *testing.T) {
+	defaultDeadline := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	first, _, err := Read(strings.NewReader(export), 1, defaultDeadline, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := Read(strings.NewReader(export), 2, defaultDeadline, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != len(other) {
+		t.Fatalf("Read() = %d and %d tasks for the same backup", len(first), len(other))
+	}
+	for i := range first {
+		if first[i].Task.Id == other[i].Task.Id {
+			t.Errorf("task %q has the same id for different owners", first[i].Key)
+		}
+	}
+}
+
+func TestReadNotBackup(t *testing.T) {
+	_, _, err := Read(strings.NewReader(`{"projects": []}`), 1, time.Time{}, time.UTC)
+	if !errors.Is(err, ErrNotBackup) {
+		t.Errorf("Read() error = %v, want ErrNotBackup", err)
+	}
+}
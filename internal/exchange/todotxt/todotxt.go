@@ -0,0 +1,303 @@
+// Package todotxt converts tasks to and from todo.txt files, one task per line.
+//
+// Priorities (A) to (C) stand for the Eisenhower quadrants, contexts for tags
+// and due: for the deadline. The hierarchy is kept in id: and parent: keys; a
+// task without a parent: goes under its first +project, which is imported as
+// a task of its own.
+package todotxt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	keyId       = "id"
+	keyParent   = "parent"
+	keyDue      = "due"
+	keyStatus   = "status"
+	keyPriority = "pri"
+	keyWeight   = "weight"
+)
+
+// Write writes a line per task. Parents are only referred to when they are
+// written too, and dates are written in loc.
+func Write(w io.Writer, tasks []*model.Task, loc *time.Location) error {
+	bw := bufio.NewWriter(w)
+
+	written := make(map[uuid.UUID]bool, len(tasks))
+	for _, task := range tasks {
+		written[task.Id] = true
+	}
+
+	for _, task := range tasks {
+		words := make([]string, 0)
+
+		priority := priorityOf(task.IsUrgent, task.IsImportant)
+		closed := task.ProgressStatus == model.ProgressStatusDone || task.ProgressStatus == model.ProgressStatusCanceled
+		if closed {
+			words = append(words, "x")
+			if completedAt := completionOf(task); completedAt != nil {
+				words = append(words, completedAt.In(loc).Format(dateLayout))
+			}
+		} else if priority != 0 {
+			words = append(words, "("+string(priority)+")")
+		}
+
+		words = append(words, strings.Fields(task.Header)...)
+		for _, tag := range task.Tags {
+			words = append(words, "@"+strings.Join(strings.Fields(tag.Name), "_"))
+		}
+
+		words = append(words, keyDue+":"+task.Deadline.In(loc).Format(dateLayout))
+		switch task.ProgressStatus {
+		case model.ProgressStatusBacklog, model.ProgressStatusDone:
+		default:
+			words = append(words, keyStatus+":"+strings.ReplaceAll(string(task.ProgressStatus), " ", "-"))
+		}
+		// completed tasks lose their priority in todo.txt, the key keeps it
+		if priority != 0 && closed {
+			words = append(words, keyPriority+":"+string(priority))
+		}
+		if task.Weight != 0 {
+			words = append(words, keyWeight+":"+strconv.Itoa(int(task.Weight)))
+		}
+		words = append(words, keyId+":"+task.Id.String())
+		if task.ParentId != nil && written[*task.ParentId] {
+			words = append(words, keyParent+":"+task.ParentId.String())
+		}
+
+		_, err := bw.WriteString(strings.Join(words, " ") + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Read reads tasks for an import by the owner. Lines are keyed by their
+// number. Tasks without due: get defaultDeadline, or are reported when it is
+// zero. Dates are read in loc. Lines that cannot be read are reported and
+// left out.
+func Read(r io.Reader, ownerId int32, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	tasks := make([]*model.ImportTask, 0)
+	lineErrors := make([]*model.ImportError, 0)
+
+	// projects are created once, in the order they are first used, and end
+	// with their last task
+	projects := make(map[string]*model.ImportTask)
+	projectOrder := make([]*model.ImportTask, 0)
+
+	n := 0
+	for scanner.Scan() {
+		n++
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("line %d", n)
+		task, project, err := parseLine(l, ownerId, defaultDeadline, loc)
+		if err != nil {
+			lineErrors = append(lineErrors, &model.ImportError{Key: key, Message: err.Error()})
+			continue
+		}
+
+		if project != "" {
+			p, ok := projects[project]
+			if !ok {
+				p = &model.ImportTask{
+					Key: "+" + project,
+					Task: &model.Task{
+						Id:             idOf(ownerId, "+"+project),
+						Header:         project,
+						ProgressStatus: model.ProgressStatusBacklog,
+					},
+				}
+				projects[project] = p
+				projectOrder = append(projectOrder, p)
+			}
+			if task.Deadline.After(p.Task.Deadline) {
+				p.Task.Deadline = task.Deadline
+				p.Task.PossibleDeadline = task.Deadline
+			}
+			task.ParentId = &p.Task.Id
+		}
+
+		tasks = append(tasks, &model.ImportTask{Key: key, Task: task})
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return append(projectOrder, tasks...), lineErrors, nil
+}
+
+// parseLine reads a task and the project it goes under, if any.
+func parseLine(l string, ownerId int32, defaultDeadline time.Time, loc *time.Location) (*model.Task, string, error) {
+	task := &model.Task{ProgressStatus: model.ProgressStatusBacklog}
+
+	words := strings.Fields(l)
+	if words[0] == "x" {
+		task.ProgressStatus = model.ProgressStatusDone
+		words = words[1:]
+		if len(words) != 0 {
+			if completedAt, err := time.ParseInLocation(dateLayout, words[0], loc); err == nil {
+				completedAt = completedAt.UTC()
+				task.CompletedAt = &completedAt
+				words = words[1:]
+			}
+		}
+	}
+
+	var priority byte
+	if len(words) != 0 && len(words[0]) == 3 && words[0][0] == '(' && words[0][2] == ')' {
+		priority = words[0][1]
+		words = words[1:]
+	}
+
+	// creation date, there is no field for it
+	if len(words) != 0 {
+		if _, err := time.Parse(dateLayout, words[0]); err == nil {
+			words = words[1:]
+		}
+	}
+
+	header := make([]string, 0, len(words))
+	projects := make([]string, 0)
+	var hasDue, hasId bool
+	for _, word := range words {
+		var err error
+		k, v, isPair := strings.Cut(word, ":")
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			projects = append(projects, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			task.Tags = append(task.Tags, &model.Tag{Name: word[1:]})
+		case isPair && k != "" && v != "" && !strings.HasPrefix(v, "//"):
+			switch k {
+			case keyId:
+				task.Id = idOf(ownerId, v)
+				hasId = true
+			case keyParent:
+				parentId := idOf(ownerId, v)
+				task.ParentId = &parentId
+			case keyDue:
+				task.Deadline, err = time.ParseInLocation(dateLayout, v, loc)
+				hasDue = true
+			case keyStatus:
+				task.ProgressStatus, err = model.ProgressStatusFromString(strings.ReplaceAll(v, "-", " "))
+			case keyPriority:
+				if len(v) == 1 {
+					priority = v[0]
+				}
+			case keyWeight:
+				var weight int64
+				weight, err = strconv.ParseInt(v, 10, 32)
+				task.Weight = int32(weight)
+			default:
+				header = append(header, word)
+			}
+		default:
+			header = append(header, word)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", k, err)
+		}
+	}
+
+	if task.ProgressStatus == model.ProgressStatusCanceled {
+		task.CanceledAt, task.CompletedAt = task.CompletedAt, nil
+	}
+
+	task.Header = strings.Join(header, " ")
+	task.IsUrgent, task.IsImportant = flagsOf(priority)
+
+	if !hasDue {
+		if defaultDeadline.IsZero() {
+			return nil, "", fmt.Errorf("%s is required", keyDue)
+		}
+		task.Deadline = defaultDeadline
+	}
+	task.Deadline = task.Deadline.UTC()
+	task.PossibleDeadline = task.Deadline
+
+	if !hasId {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, "", err
+		}
+		task.Id = id
+	}
+
+	project := ""
+	if task.ParentId == nil && len(projects) != 0 {
+		project = projects[0]
+		projects = projects[1:]
+	}
+	for _, p := range projects {
+		task.Tags = append(task.Tags, &model.Tag{Name: p})
+	}
+
+	return task, project, nil
+}
+
+// completionOf returns when a done or canceled task was closed.
+func completionOf(task *model.Task) *time.Time {
+	if task.ProgressStatus == model.ProgressStatusCanceled {
+		return task.CanceledAt
+	}
+	return task.CompletedAt
+}
+
+// idOf returns the UUID an id: or parent: value of the owner stands for, so
+// that ids that are not UUIDs still find the same tasks on every import and
+// do not collide with the same ids of other owners.
+func idOf(ownerId int32, v string) uuid.UUID {
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("todotxt:%d:%s", ownerId, v)))
+	}
+	return id
+}
+
+// priorityOf maps the Eisenhower quadrant to a priority, 0 being none.
+func priorityOf(isUrgent bool, isImportant bool) byte {
+	switch model.QuadrantOf(isUrgent, isImportant) {
+	case model.QuadrantDo:
+		return 'A'
+	case model.QuadrantSchedule:
+		return 'B'
+	case model.QuadrantDelegate:
+		return 'C'
+	default:
+		return 0
+	}
+}
+
+func flagsOf(priority byte) (isUrgent bool, isImportant bool) {
+	switch priority {
+	case 'A':
+		return true, true
+	case 'B':
+		return false, true
+	case 'C':
+		return true, false
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,226 @@
+package todotxt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseLine(t *testing.T) {
+	defaultDeadline := date(2024, time.April, 1)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    func(t *testing.T, task *model.Task, project string)
+		wantErr bool
+	}{
+		{
+			name: "priority, creation date and keys",
+			in:   "(A) 2024-03-01 Call mom due:2024-03-10 weight:3 status:in-progress",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if task.Header != "Call mom" {
+					t.Errorf("header = %q, want %q", task.Header, "Call mom")
+				}
+				if !task.IsUrgent || !task.IsImportant {
+					t.Errorf("flags = %v, %v, want urgent and important", task.IsUrgent, task.IsImportant)
+				}
+				if !task.Deadline.Equal(date(2024, time.March, 10)) {
+					t.Errorf("deadline = %v, want 2024-03-10", task.Deadline)
+				}
+				if task.Weight != 3 || task.ProgressStatus != model.ProgressStatusInProgress {
+					t.Errorf("weight, status = %d, %q, want 3, in progress", task.Weight, task.ProgressStatus)
+				}
+			},
+		},
+		{
+			name: "completed with priority key",
+			in:   "x 2024-03-05 Pay rent pri:B",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if task.ProgressStatus != model.ProgressStatusDone {
+					t.Errorf("status = %q, want done", task.ProgressStatus)
+				}
+				if task.CompletedAt == nil || !task.CompletedAt.Equal(date(2024, time.March, 5)) {
+					t.Errorf("completed at = %v, want 2024-03-05", task.CompletedAt)
+				}
+				if task.IsUrgent || !task.IsImportant {
+					t.Errorf("flags = %v, %v, want important only", task.IsUrgent, task.IsImportant)
+				}
+				if !task.Deadline.Equal(defaultDeadline) {
+					t.Errorf("deadline = %v, want the default %v", task.Deadline, defaultDeadline)
+				}
+			},
+		},
+		{
+			name: "canceled keeps its closing date",
+			in:   "x 2024-03-05 Trip status:canceled",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if task.CanceledAt == nil || task.CompletedAt != nil {
+					t.Errorf("canceled at, completed at = %v, %v, want only canceled at", task.CanceledAt, task.CompletedAt)
+				}
+			},
+		},
+		{
+			name: "projects and contexts",
+			in:   "Buy milk +home +errands @shop",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if project != "home" {
+					t.Errorf("project = %q, want home", project)
+				}
+				names := make([]string, 0, len(task.Tags))
+				for _, tag := range task.Tags {
+					names = append(names, tag.Name)
+				}
+				if strings.Join(names, " ") != "shop errands" {
+					t.Errorf("tags = %q, want shop and errands", names)
+				}
+			},
+		},
+		{
+			name: "parent wins over project",
+			in:   "Sub +home parent:1",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if project != "" || task.ParentId == nil || *task.ParentId != idOf(1, "1") {
+					t.Errorf("project, parent = %q, %v, want none, %v", project, task.ParentId, idOf(1, "1"))
+				}
+			},
+		},
+		{
+			name: "urls and unknown keys stay in the header",
+			in:   "Read https://example.com note:later",
+			want: func(t *testing.T, task *model.Task, project string) {
+				if task.Header != "Read https://example.com note:later" {
+					t.Errorf("header = %q", task.Header)
+				}
+			},
+		},
+		{name: "malformed due", in: "Task due:tomorrow", wantErr: true},
+		{name: "unknown status", in: "Task status:paused", wantErr: true},
+		{name: "malformed weight", in: "Task weight:heavy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, project, err := parseLine(tt.in, 1, defaultDeadline, time.UTC)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseLine(%q) error = nil, want one", tt.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLine(%q) error = %v", tt.in, err)
+			}
+			tt.want(t, task, project)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	in := "Plan due:2024-03-10 +work id:plan\n" +
+		"\n" +
+		"Ship due:2024-03-20 +work\n" +
+		"Review due:2024-03-15 parent:plan\n" +
+		"Undated\n"
+
+	tasks, lineErrors, err := Read(strings.NewReader(in), 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lineErrors) != 1 || lineErrors[0].Key != "line 5" {
+		t.Errorf("Read() errors = %v, want one for line 5", lineErrors)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("Read() = %d tasks, want 4", len(tasks))
+	}
+
+	project, plan, ship, review := tasks[0].Task, tasks[1].Task, tasks[2].Task, tasks[3].Task
+	if tasks[0].Key != "+work" || project.Header != "work" {
+		t.Errorf("first task = %q %q, want the work project", tasks[0].Key, project.Header)
+	}
+	if !project.Deadline.Equal(date(2024, time.March, 20)) {
+		t.Errorf("project deadline = %v, want its last task's", project.Deadline)
+	}
+	for _, task := range []*model.Task{plan, ship} {
+		if task.ParentId == nil || *task.ParentId != project.Id {
+			t.Errorf("task %q parent = %v, want the project", task.Header, task.ParentId)
+		}
+	}
+	if review.ParentId == nil || *review.ParentId != plan.Id {
+		t.Errorf("review parent = %v, want plan %v", review.ParentId, plan.Id)
+	}
+
+	other, _, err := Read(strings.NewReader(in), 2, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other[0].Task.Id == project.Id || other[1].Task.Id == plan.Id {
+		t.Errorf("Read() derived the same ids for different owners")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	completedAt := date(2024, time.March, 5)
+	parent := &model.Task{
+		Id:             uuid.New(),
+		Header:         "Renovate the kitchen",
+		Deadline:       date(2024, time.March, 30),
+		ProgressStatus: model.ProgressStatusOnHold,
+		IsImportant:    true,
+		Weight:         8,
+		Tags:           []*model.Tag{{Name: "home"}},
+	}
+	child := &model.Task{
+		Id:             uuid.New(),
+		ParentId:       &parent.Id,
+		Header:         "Buy tiles",
+		Deadline:       date(2024, time.March, 10),
+		ProgressStatus: model.ProgressStatusDone,
+		CompletedAt:    &completedAt,
+		IsUrgent:       true,
+		IsImportant:    true,
+	}
+
+	var b bytes.Buffer
+	err := Write(&b, []*model.Task{parent, child}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tasks, lineErrors, err := Read(&b, 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lineErrors) != 0 || len(tasks) != 2 {
+		t.Fatalf("Read() = %d tasks, %v errors, want 2 tasks", len(tasks), lineErrors)
+	}
+
+	for i, want := range []*model.Task{parent, child} {
+		got := tasks[i].Task
+		if got.Id != want.Id || got.Header != want.Header || !got.Deadline.Equal(want.Deadline) {
+			t.Errorf("Read()[%d] = %v %q %v, want %v %q %v", i, got.Id, got.Header, got.Deadline, want.Id, want.Header, want.Deadline)
+		}
+		if got.ProgressStatus != want.ProgressStatus || got.IsUrgent != want.IsUrgent || got.IsImportant != want.IsImportant {
+			t.Errorf("Read()[%d] = %q, %v, %v, want %q, %v, %v", i,
+				got.ProgressStatus, got.IsUrgent, got.IsImportant, want.ProgressStatus, want.IsUrgent, want.IsImportant)
+		}
+		if got.Weight != want.Weight || len(got.Tags) != len(want.Tags) {
+			t.Errorf("Read()[%d] weight, tags = %d, %d, want %d, %d", i, got.Weight, len(got.Tags), want.Weight, len(want.Tags))
+		}
+	}
+	if tasks[1].Task.ParentId == nil || *tasks[1].Task.ParentId != parent.Id {
+		t.Errorf("child parent = %v, want %v", tasks[1].Task.ParentId, parent.Id)
+	}
+	if tasks[1].Task.CompletedAt == nil || !tasks[1].Task.CompletedAt.Equal(completedAt) {
+		t.Errorf("child completed at = %v, want %v", tasks[1].Task.CompletedAt, completedAt)
+	}
+}
@@ -0,0 +1,189 @@
+// Package trello reads the JSON export of a Trello board for an import.
+//
+// Every open list becomes a task with its open cards as subtasks. Checklists
+// of a card become its checklist, labels its tags and attachments its external
+// images. Archived lists and cards are left out.
+package trello
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/google/uuid"
+)
+
+var ErrNotBoard = errors.New("input is not a Trello board export")
+
+type board struct {
+	Id         string      `json:"id"`
+	Lists      []list      `json:"lists"`
+	Cards      []card      `json:"cards"`
+	Checklists []checklist `json:"checklists"`
+}
+
+type list struct {
+	Id     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type card struct {
+	Id          string     `json:"id"`
+	IdList      string     `json:"idList"`
+	Name        string     `json:"name"`
+	Desc        string     `json:"desc"`
+	Closed      bool       `json:"closed"`
+	Pos         float64    `json:"pos"`
+	Due         *time.Time `json:"due"`
+	DueComplete bool       `json:"dueComplete"`
+	Labels      []struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Attachments []struct {
+		Url string `json:"url"`
+	} `json:"attachments"`
+}
+
+type checklist struct {
+	IdCard     string  `json:"idCard"`
+	Pos        float64 `json:"pos"`
+	CheckItems []struct {
+		Name  string  `json:"name"`
+		State string  `json:"state"`
+		Pos   float64 `json:"pos"`
+	} `json:"checkItems"`
+}
+
+// Read reads a board for an import by the owner. Lists and cards are keyed by
+// their name and get ids derived from the owner and their Trello ids, so
+// importing the same board again finds the same tasks. Cards without a due date get defaultDeadline, or are
+// reported when it is zero, and a list is due with its last card. loc is not
+// used, Trello dates are in UTC.
+func Read(r io.Reader, ownerId int32, defaultDeadline time.Time, loc *time.Location) ([]*model.ImportTask, []*model.ImportError, error) {
+	b := board{}
+	err := json.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.Id == "" || b.Lists == nil {
+		return nil, nil, ErrNotBoard
+	}
+
+	sort.SliceStable(b.Lists, func(i, j int) bool { return b.Lists[i].Pos < b.Lists[j].Pos })
+	sort.SliceStable(b.Cards, func(i, j int) bool { return b.Cards[i].Pos < b.Cards[j].Pos })
+	sort.SliceStable(b.Checklists, func(i, j int) bool { return b.Checklists[i].Pos < b.Checklists[j].Pos })
+
+	checklists := make(map[string][]*model.ChecklistItem)
+	for _, c := range b.Checklists {
+		sort.SliceStable(c.CheckItems, func(i, j int) bool { return c.CheckItems[i].Pos < c.CheckItems[j].Pos })
+		for _, item := range c.CheckItems {
+			checklists[c.IdCard] = append(checklists[c.IdCard], &model.ChecklistItem{
+				Text:      item.Name,
+				IsChecked: item.State == "complete",
+			})
+		}
+	}
+
+	lists := make(map[string]*model.ImportTask, len(b.Lists))
+	tasks := make([]*model.ImportTask, 0, len(b.Lists)+len(b.Cards))
+	cardErrors := make([]*model.ImportError, 0)
+	for _, l := range b.Lists {
+		if l.Closed {
+			continue
+		}
+		t := &model.ImportTask{
+			Key: "list " + l.Name,
+			Task: &model.Task{
+				Id:             idOf(ownerId, l.Id),
+				Header:         l.Name,
+				ProgressStatus: model.ProgressStatusBacklog,
+			},
+		}
+		lists[l.Id] = t
+		tasks = append(tasks, t)
+	}
+
+	for _, c := range b.Cards {
+		parent, ok := lists[c.IdList]
+		if c.Closed || !ok {
+			continue
+		}
+
+		key := "card " + c.Name
+		task := &model.Task{
+			Id:             idOf(ownerId, c.Id),
+			Header:         c.Name,
+			Text:           c.Desc,
+			ProgressStatus: model.ProgressStatusBacklog,
+			ParentId:       &parent.Task.Id,
+			ExternalImages: make([]string, 0, len(c.Attachments)),
+			Tags:           make([]*model.Tag, 0, len(c.Labels)),
+			Checklist:      checklists[c.Id],
+		}
+
+		switch {
+		case c.Due != nil:
+			task.Deadline = c.Due.UTC()
+		case !defaultDeadline.IsZero():
+			task.Deadline = defaultDeadline.UTC()
+		default:
+			cardErrors = append(cardErrors, &model.ImportError{Key: key, Message: "due date is required"})
+			continue
+		}
+		task.PossibleDeadline = task.Deadline
+
+		if c.DueComplete {
+			task.ProgressStatus = model.ProgressStatusDone
+		}
+
+		for _, label := range c.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			if name != "" {
+				task.Tags = append(task.Tags, &model.Tag{Name: name})
+			}
+		}
+
+		for _, attachment := range c.Attachments {
+			if attachment.Url != "" {
+				task.ExternalImages = append(task.ExternalImages, attachment.Url)
+			}
+		}
+
+		if task.Deadline.After(parent.Task.Deadline) {
+			parent.Task.Deadline = task.Deadline
+			parent.Task.PossibleDeadline = task.Deadline
+		}
+
+		tasks = append(tasks, &model.ImportTask{Key: key, Task: task})
+	}
+
+	// lists without a card to take a deadline from
+	read := make([]*model.ImportTask, 0, len(tasks))
+	for _, t := range tasks {
+		if t.Task.Deadline.IsZero() {
+			if defaultDeadline.IsZero() {
+				cardErrors = append(cardErrors, &model.ImportError{Key: t.Key, Message: "due date is required"})
+				continue
+			}
+			t.Task.Deadline = defaultDeadline.UTC()
+			t.Task.PossibleDeadline = t.Task.Deadline
+		}
+		read = append(read, t)
+	}
+
+	return read, cardErrors, nil
+}
+
+func idOf(ownerId int32, trelloId string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("trello:%d:%s", ownerId, trelloId)))
+}
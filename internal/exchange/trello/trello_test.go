@@ -0,0 +1,126 @@
+package trello
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+const export = `{
+	"id": "b1",
+	"lists": [
+		{"id": "l2", "name": "Done", "pos": 2},
+		{"id": "l1", "name": "To do", "pos": 1},
+		{"id": "l3", "name": "Archive", "closed": true, "pos": 3},
+		{"id": "l4", "name": "Ideas", "pos": 4}
+	],
+	"cards": [
+		{"id": "c2", "idList": "l1", "name": "Second", "pos": 2, "due": "2024-03-12T09:00:00.000Z",
+			"labels": [{"name": "", "color": "red"}, {"name": "home", "color": "green"}]},
+		{"id": "c1", "idList": "l1", "name": "First", "desc": "details", "pos": 1, "due": "2024-03-10T09:00:00.000Z",
+			"attachments": [{"url": "https://example.com/a.png"}]},
+		{"id": "c3", "idList": "l2", "name": "Shipped", "pos": 1, "due": "2024-03-01T09:00:00.000Z", "dueComplete": true},
+		{"id": "c4", "idList": "l3", "name": "Archived list", "pos": 1, "due": "2024-03-01T09:00:00.000Z"},
+		{"id": "c5", "idList": "l1", "name": "Archived card", "closed": true, "pos": 3},
+		{"id": "c6", "idList": "l1", "name": "Undated", "pos": 4}
+	],
+	"checklists": [
+		{"idCard": "c1", "pos": 1, "checkItems": [
+			{"name": "b", "state": "incomplete", "pos": 2},
+			{"name": "a", "state": "complete", "pos": 1}
+		]}
+	]
+}`
+
+func TestRead(t *testing.T) {
+	tasks, cardErrors, err := Read(strings.NewReader(export), 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 0, len(tasks))
+	byKey := make(map[string]*model.Task, len(tasks))
+	for _, imported := range tasks {
+		keys = append(keys, imported.Key)
+		byKey[imported.Key] = imported.Task
+	}
+	want := "list To do, list Done, card First, card Shipped, card Second"
+	if strings.Join(keys, ", ") != want {
+		t.Errorf("Read() keys = %q, want %q", keys, want)
+	}
+
+	errorKeys := make([]string, 0, len(cardErrors))
+	for _, e := range cardErrors {
+		errorKeys = append(errorKeys, e.Key)
+	}
+	if strings.Join(errorKeys, ", ") != "card Undated, list Ideas" {
+		t.Errorf("Read() errors for %q, want the undated card and the empty list", errorKeys)
+	}
+
+	todo, first, second, shipped := byKey["list To do"], byKey["card First"], byKey["card Second"], byKey["card Shipped"]
+	if todo == nil || first == nil || second == nil || shipped == nil {
+		t.Fatal("Read() left out open lists or cards")
+	}
+	if first.ParentId == nil || *first.ParentId != todo.Id {
+		t.Errorf("card parent = %v, want its list %v", first.ParentId, todo.Id)
+	}
+	if !todo.Deadline.Equal(second.Deadline) {
+		t.Errorf("list deadline = %v, want its last card's %v", todo.Deadline, second.Deadline)
+	}
+	if first.Text != "details" || len(first.ExternalImages) != 1 {
+		t.Errorf("card text, images = %q, %v", first.Text, first.ExternalImages)
+	}
+	if len(first.Checklist) != 2 || first.Checklist[0].Text != "a" || !first.Checklist[0].IsChecked {
+		t.Errorf("card checklist is not read in order with its states")
+	}
+	if len(second.Tags) != 2 || second.Tags[0].Name != "red" || second.Tags[1].Name != "home" {
+		t.Errorf("card tags = %v, want red and home", second.Tags)
+	}
+	if shipped.ProgressStatus != model.ProgressStatusDone || first.ProgressStatus != model.ProgressStatusBacklog {
+		t.Errorf("statuses = %q, %q, want done and backlog", shipped.ProgressStatus, first.ProgressStatus)
+	}
+}
+
+func TestReadDefaultDeadline(t *testing.T) {
+	defaultDeadline := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	tasks, cardErrors, err := Read(strings.NewReader(export), 1, defaultDeadline, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cardErrors) != 0 || len(tasks) != 7 {
+		t.Fatalf("Read() = %d tasks, %v errors, want 7 tasks", len(tasks), cardErrors)
+	}
+}
+
+func TestReadOwners(t *testing.T) {
+	first, _, err := Read(strings.NewReader(export), 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := Read(strings.NewReader(export), 1, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := Read(strings.NewReader(export), 2, time.Time{}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first[0].Task.Id != again[0].Task.Id {
+		t.Errorf("Read() ids differ between imports of the same board")
+	}
+	if first[0].Task.Id == other[0].Task.Id {
+		t.Errorf("Read() ids are the same for different owners")
+	}
+}
+
+func TestReadNotBoard(t *testing.T) {
+	_, _, err := Read(strings.NewReader(`{"name": "not a board"}`), 1, time.Time{}, time.UTC)
+	if !errors.Is(err, ErrNotBoard) {
+		t.Errorf("Read() error = %v, want ErrNotBoard", err)
+	}
+}
@@ -2,10 +2,13 @@ package create
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/grpc/mapper"
+	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
 	"github.com/google/uuid"
 	proto "github.com/pyramidum-space/protos/gen/go/tasks"
 	"google.golang.org/grpc/codes"
@@ -31,7 +34,13 @@ type TaskCreator interface {
 	) (uuid.UUID, error)
 }
 
-func MakeCreateHandler(log *slog.Logger, creator TaskCreator) HandlerFunc {
+type DeadlineSuggester interface {
+	SuggestPossibleDeadlineContext(ctx context.Context, ownerId int32, weight int32) (time.Time, error)
+}
+
+// MakeCreateHandler makes the Create handler. A nil suggester leaves the
+// possible deadline of tasks created without one at its zero value.
+func MakeCreateHandler(log *slog.Logger, creator TaskCreator, suggester DeadlineSuggester) HandlerFunc {
 	const op = "grpc.handlers.create.MakeCreateHandler"
 
 	log = log.With(
@@ -54,6 +63,19 @@ func MakeCreateHandler(log *slog.Logger, creator TaskCreator) HandlerFunc {
 			}
 		}
 
+		// without a possible deadline, suggest one from the owner's pace if
+		// enabled and fall back to the old zero value when there is no history
+		// yet
+		possibleDeadline := req.PossibleDeadline.AsTime()
+		if req.PossibleDeadline == nil && suggester != nil {
+			suggested, err := suggester.SuggestPossibleDeadlineContext(ctx, req.OwnerId, req.Weight)
+			if err == nil {
+				possibleDeadline = suggested
+			} else if !errors.Is(err, model.ErrNoThroughput) {
+				log.Warn("error suggesting possible deadline", slogattr.Err(err))
+			}
+		}
+
 		id, err := creator.CreateTaskContext(
 			ctx,
 			req.Header,
@@ -65,7 +87,7 @@ func MakeCreateHandler(log *slog.Logger, creator TaskCreator) HandlerFunc {
 			req.IsImportant,
 			req.OwnerId,
 			parentUUID,
-			req.PossibleDeadline.AsTime(),
+			possibleDeadline,
 			req.Weight,
 		)
 
@@ -2,10 +2,13 @@ package patch
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"time"
 
+	repository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/grpc/mapper"
 	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
 	"github.com/google/uuid"
@@ -111,6 +114,12 @@ func MakePatchHandler(log *slog.Logger, provider TaskUpdater) HandlerFunc {
 
 		if err != nil {
 			log.Error("error updating task", slogattr.Err(err))
+			if errors.Is(err, workflow.ErrTransitionNotAllowed) {
+				return nil, status.Error(codes.FailedPrecondition, err.Error())
+			}
+			if errors.Is(err, repository.ErrParentCycle) {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
 			return nil, status.Error(codes.Internal, err.Error())
 		}
 
@@ -31,6 +31,7 @@ type TaskProvider interface {
 		isImportant *bool,
 		weightFrom *int32,
 		weightTo *int32,
+		isOverdue *bool,
 	) ([]*model.Task, error)
 }
 
@@ -95,6 +96,8 @@ func MakeTasksHandler(log *slog.Logger, provider TaskProvider) HandlerFunc {
 			req.IsImportant,
 			req.WeightFrom,
 			req.WeightTo,
+			// TasksRequest has no overdue filter yet
+			nil,
 		)
 		if err != nil {
 			log.Error("error getting tasks", slogattr.Err(err))
@@ -2,9 +2,12 @@ package update
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 
+	repository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
 	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/grpc/mapper"
 	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
 	"github.com/google/uuid"
@@ -68,6 +71,12 @@ func MakeUpdateHandler(log *slog.Logger, provider TaskUpdater) HandlerFunc {
 
 		if err := provider.UpdateTaskContext(ctx, &mTask); err != nil {
 			log.Error("error updating task", slogattr.Err(err))
+			if errors.Is(err, workflow.ErrTransitionNotAllowed) {
+				return nil, status.Error(codes.FailedPrecondition, err.Error())
+			}
+			if errors.Is(err, repository.ErrParentCycle) {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
 			return nil, status.Error(codes.Internal, err.Error())
 		}
 
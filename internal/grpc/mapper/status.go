@@ -19,9 +19,11 @@ func ProtoProgressStatusToString(status proto.ProgressStatus) (string, error) {
 	}
 }
 
+// StringToProgressStatus maps statuses the proto enum does not have yet (backlog,
+// blocked and on hold) to PROGRESS_STATUS_IN_PROGRESS, as they are all open.
 func StringToProgressStatus(s string) (proto.ProgressStatus, error) {
 	switch s {
-	case "in progress":
+	case "in progress", "backlog", "blocked", "on hold":
 		return proto.ProgressStatus_PROGRESS_STATUS_IN_PROGRESS, nil
 	case "canceled":
 		return proto.ProgressStatus_PROGRESS_STATUS_CANCELED, nil
@@ -45,11 +47,17 @@ func ProtoProgressStatusToModelProgressStatus(status proto.ProgressStatus) (mode
 	}
 }
 
+// ModelProgressStatusToProtoProgressStatus maps statuses the proto enum does not
+// have yet (backlog, blocked and on hold) to PROGRESS_STATUS_IN_PROGRESS, as they
+// are all open.
 func ModelProgressStatusToProtoProgressStatus(status model.ProgressStatus) (proto.ProgressStatus, error) {
 	switch status {
 	case model.ProgressStatusCanceled:
 		return proto.ProgressStatus_PROGRESS_STATUS_CANCELED, nil
-	case model.ProgressStatusInProgress:
+	case model.ProgressStatusInProgress,
+		model.ProgressStatusBacklog,
+		model.ProgressStatusBlocked,
+		model.ProgressStatusOnHold:
 		return proto.ProgressStatus_PROGRESS_STATUS_IN_PROGRESS, nil
 	case model.ProgressStatusDone:
 		return proto.ProgressStatus_PROGRESS_STATUS_DONE, nil
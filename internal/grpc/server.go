@@ -23,11 +23,18 @@ type ServerAPI struct {
 	patchHandlerFunc  patch.HandlerFunc
 }
 
-func RegisterServer(gRPC *grpc.Server, log *slog.Logger, r *repository.Repository) {
+// RegisterServer registers the tasks service backed by r. Create suggests
+// possible deadlines only when suggestPossibleDeadline is set.
+func RegisterServer(gRPC *grpc.Server, log *slog.Logger, r *repository.Repository, suggestPossibleDeadline bool) {
+	var suggester create.DeadlineSuggester
+	if suggestPossibleDeadline {
+		suggester = r
+	}
+
 	proto.RegisterTasksServiceServer(
 		gRPC,
 		&ServerAPI{
-			createHandlerFunc: create.MakeCreateHandler(log, r),
+			createHandlerFunc: create.MakeCreateHandler(log, r, suggester),
 			taskHandlerFunc:   task.MakeTaskHandler(log, r),
 			tasksHandlerFunc:  tasks.MakeTasksHandler(log, r),
 			updateHandlerFunc: update.MakeUpdateHandler(log, r),
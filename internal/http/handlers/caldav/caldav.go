@@ -0,0 +1,459 @@
+// Package caldav serves the tasks of an owner as a CalDAV calendar of VTODO
+// resources, so that calendar clients can sync them both ways.
+//
+// The layout is /caldav/ for discovery, /caldav/{owner}/ for the principal and
+// its calendar home, and /caldav/{owner}/tasks/{id}.ics for the tasks. Clients
+// authenticate with HTTP Basic and a CalDAV token as the password. ETags are
+// task versions.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	repository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/workflow"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/ical"
+	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
+	"github.com/google/uuid"
+)
+
+const (
+	// Root is the path the handler is mounted at.
+	Root = "/caldav/"
+
+	collectionName = "tasks"
+	resourceSuffix = ".ics"
+
+	calendarContentType = "text/calendar; charset=utf-8"
+
+	// maxCalendarSize bounds the body of a PUT.
+	maxCalendarSize = 1 << 20
+
+	// defaultDue is how long after its creation a task put without DUE is due.
+	defaultDue = 7 * 24 * time.Hour
+)
+
+type Repository interface {
+	TokenOwnerContext(ctx context.Context, token string, scope model.FeedTokenScope) (int32, error)
+	ExportTasksContext(ctx context.Context, ownerId int32) ([]*model.Task, error)
+	ExportTaskContext(ctx context.Context, id uuid.UUID) (*model.Task, error)
+	ImportTasksContext(
+		ctx context.Context,
+		ownerId int32,
+		tasks []*model.ImportTask,
+		conflict model.ImportConflict,
+		dryRun bool,
+	) (*model.ImportReport, error)
+	UpdateTaskContext(ctx context.Context, task *model.Task) error
+	UpdateTaskVersionContext(ctx context.Context, task *model.Task, version int64) error
+	DeleteTaskContext(ctx context.Context, id uuid.UUID, version *int64) error
+}
+
+type targetKind int
+
+const (
+	targetRoot targetKind = iota
+	targetPrincipal
+	targetCollection
+	targetResource
+)
+
+// target is what a request path points at.
+type target struct {
+	kind    targetKind
+	ownerId int32
+	id      uuid.UUID
+}
+
+type handler struct {
+	log        *slog.Logger
+	repository Repository
+}
+
+func MakeCalDAVHandler(log *slog.Logger, repository Repository) http.HandlerFunc {
+	const op = "http.handlers.caldav.MakeCalDAVHandler"
+
+	h := &handler{
+		log: log.With(
+			slog.String("op", op),
+		),
+		repository: repository,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			w.Header().Set("DAV", "1, 3, calendar-access")
+			w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+			return
+		}
+
+		ownerId, ok := h.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		t, ok := parseTarget(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if t.kind == targetRoot {
+			t.ownerId = ownerId
+		}
+		if t.ownerId != ownerId {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		switch {
+		case r.Method == "PROPFIND":
+			h.propfind(w, r, t)
+		case r.Method == "REPORT" && t.kind == targetCollection:
+			h.report(w, r, t)
+		case (r.Method == http.MethodGet || r.Method == http.MethodHead) && t.kind == targetResource:
+			h.get(w, r, t)
+		case r.Method == http.MethodPut && t.kind == targetResource:
+			h.put(w, r, t)
+		case r.Method == http.MethodDelete && t.kind == targetResource:
+			h.delete(w, r, t)
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// authenticate returns the owner of the CalDAV token given as the Basic
+// password, or asks for credentials.
+func (h *handler) authenticate(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	_, token, ok := r.BasicAuth()
+	if ok {
+		ownerId, err := h.repository.TokenOwnerContext(r.Context(), token, model.FeedTokenScopeCalDAV)
+		if err == nil {
+			return ownerId, true
+		}
+		if !errors.Is(err, repository.ErrFeedTokenNotFound) {
+			h.fail(w, err)
+			return 0, false
+		}
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="pyramidum", charset="UTF-8"`)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return 0, false
+}
+
+func (h *handler) get(w http.ResponseWriter, r *http.Request, t target) {
+	task, err := h.task(r.Context(), t.ownerId, t.id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	body, err := encode(task)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	etag := etagOf(task)
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.Write(body)
+}
+
+// put creates or replaces a task from a calendar holding one VTODO whose UID
+// names the resource. A VTODO without DUE, X-PYRAMIDUM-POSSIBLE-DEADLINE,
+// X-PYRAMIDUM-WEIGHT or ATTACH keeps those values of a stored task, a new one
+// is due defaultDue after it is created. A new task gets its tags from
+// CATEGORIES; tags, the checklist and the other relations of a stored task
+// are kept.
+func (h *handler) put(w http.ResponseWriter, r *http.Request, t target) {
+	ctx := r.Context()
+
+	stored, err := h.task(ctx, t.ownerId, t.id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.fail(w, err)
+		return
+	}
+
+	defaults := ical.Defaults{Deadline: time.Now().Add(defaultDue).UTC()}
+	if stored != nil {
+		defaults = ical.Defaults{
+			Deadline:         stored.Deadline,
+			PossibleDeadline: stored.PossibleDeadline,
+			Weight:           stored.Weight,
+			ExternalImages:   stored.ExternalImages,
+		}
+	}
+
+	tasks, todoErrors, err := ical.Decode(
+		http.MaxBytesReader(w, r.Body, maxCalendarSize),
+		t.ownerId,
+		defaults,
+		time.UTC,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(todoErrors) != 0 {
+		http.Error(w, todoErrors[0].Error(), http.StatusBadRequest)
+		return
+	}
+	if len(tasks) != 1 {
+		http.Error(w, "exactly one VTODO is required", http.StatusBadRequest)
+		return
+	}
+	put := tasks[0].Task
+	if put.Id != t.id {
+		http.Error(w, "UID does not match the resource name", http.StatusBadRequest)
+		return
+	}
+
+	if put.ParentId != nil {
+		if *put.ParentId == t.id {
+			http.Error(w, "task cannot be its own parent", http.StatusConflict)
+			return
+		}
+		_, err = h.task(ctx, t.ownerId, *put.ParentId)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "unknown parent", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if stored == nil {
+		if ifMatch != "" {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+		if !h.create(ctx, w, t.ownerId, tasks[0]) {
+			return
+		}
+	} else {
+		if r.Header.Get("If-None-Match") == "*" {
+			http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+
+		stored.Header = put.Header
+		stored.Text = put.Text
+		stored.Deadline = put.Deadline
+		stored.PossibleDeadline = put.PossibleDeadline
+		stored.ProgressStatus = put.ProgressStatus
+		stored.IsUrgent = put.IsUrgent
+		stored.IsImportant = put.IsImportant
+		stored.ParentId = put.ParentId
+		stored.Weight = put.Weight
+		stored.ExternalImages = put.ExternalImages
+		// nil tags keep the current ones
+		stored.Tags = nil
+
+		if ifMatch == "" || ifMatch == "*" {
+			err = h.repository.UpdateTaskContext(ctx, stored)
+		} else if version, ok := versionOf(ifMatch); ok {
+			err = h.repository.UpdateTaskVersionContext(ctx, stored, version)
+		} else {
+			err = repository.ErrVersionMismatch
+		}
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+	}
+
+	task, err := h.task(ctx, t.ownerId, t.id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etagOf(task))
+	if stored == nil {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// create imports a new task, reporting whether it was created.
+func (h *handler) create(ctx context.Context, w http.ResponseWriter, ownerId int32, task *model.ImportTask) bool {
+	report, err := h.repository.ImportTasksContext(
+		ctx,
+		ownerId,
+		[]*model.ImportTask{task},
+		model.ImportConflictSkip,
+		false,
+	)
+	if err != nil {
+		h.fail(w, err)
+		return false
+	}
+	if len(report.Errors) != 0 {
+		http.Error(w, report.Errors[0].Error(), http.StatusConflict)
+		return false
+	}
+	if report.Skipped != 0 {
+		// created by someone else in the meantime
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// delete deletes the task with its subtasks.
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, t target) {
+	ctx := r.Context()
+
+	_, err := h.task(ctx, t.ownerId, t.id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	var version *int64
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		v, ok := versionOf(ifMatch)
+		if !ok {
+			h.fail(w, repository.ErrVersionMismatch)
+			return
+		}
+		version = &v
+	}
+
+	err = h.repository.DeleteTaskContext(ctx, t.id, version)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// task returns a task of the owner, tasks of other owners are not found.
+func (h *handler) task(ctx context.Context, ownerId int32, id uuid.UUID) (*model.Task, error) {
+	task, err := h.repository.ExportTaskContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.OwnerId != ownerId {
+		return nil, sql.ErrNoRows
+	}
+
+	return task, nil
+}
+
+// fail answers with the status an error stands for, logging unexpected ones.
+func (h *handler) fail(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case errors.Is(err, repository.ErrVersionMismatch):
+		http.Error(w, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+	case errors.Is(err, workflow.ErrTransitionNotAllowed), errors.Is(err, repository.ErrParentCycle):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error("error serving caldav request", slogattr.Err(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// parseTarget finds what a path below Root points at.
+func parseTarget(path string) (target, bool) {
+	rest, ok := strings.CutPrefix(path, Root)
+	if !ok {
+		return target{}, false
+	}
+	rest = strings.TrimSuffix(rest, "/")
+	if rest == "" {
+		return target{kind: targetRoot}, true
+	}
+
+	segments := strings.Split(rest, "/")
+	ownerId, err := strconv.ParseInt(segments[0], 10, 32)
+	if err != nil {
+		return target{}, false
+	}
+	t := target{kind: targetPrincipal, ownerId: int32(ownerId)}
+
+	switch {
+	case len(segments) == 1:
+		return t, true
+	case segments[1] != collectionName:
+		return target{}, false
+	case len(segments) == 2:
+		t.kind = targetCollection
+		return t, true
+	case len(segments) == 3 && strings.HasSuffix(segments[2], resourceSuffix):
+		t.kind = targetResource
+		t.id = ical.IdOf(t.ownerId, strings.TrimSuffix(segments[2], resourceSuffix))
+		return t, true
+	default:
+		return target{}, false
+	}
+}
+
+func principalHref(ownerId int32) string {
+	return fmt.Sprintf("%s%d/", Root, ownerId)
+}
+
+func collectionHref(ownerId int32) string {
+	return principalHref(ownerId) + collectionName + "/"
+}
+
+func resourceHref(task *model.Task) string {
+	return collectionHref(task.OwnerId) + task.Id.String() + resourceSuffix
+}
+
+func etagOf(task *model.Task) string {
+	return `"` + strconv.FormatInt(task.Version, 10) + `"`
+}
+
+// versionOf reads the task version of a strong ETag.
+func versionOf(etag string) (int64, bool) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return version, true
+}
+
+// encode renders the task as a calendar resource. DTSTAMP is the time of the
+// last change, so equal versions have equal bodies.
+func encode(task *model.Task) ([]byte, error) {
+	var body bytes.Buffer
+	err := ical.Encode(&body, []*model.Task{task}, task.ModifiedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Bytes(), nil
+}
@@ -0,0 +1,415 @@
+package caldav
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+
+	// maxRequestSize bounds the body of a PROPFIND or REPORT.
+	maxRequestSize = 1 << 20
+)
+
+// prefixes are the namespace prefixes multistatus responses declare.
+var prefixes = map[string]string{
+	nsDAV:            "d",
+	nsCalDAV:         "c",
+	nsCalendarServer: "cs",
+}
+
+var (
+	propCalendarData = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propGetETag      = xml.Name{Space: nsDAV, Local: "getetag"}
+)
+
+// properties maps the properties of a resource to their XML content.
+type properties map[xml.Name]string
+
+// propList is the DAV:prop element of a request.
+type propList struct {
+	Props []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (l *propList) names() []xml.Name {
+	names := make([]xml.Name, 0, len(l.Props))
+	for _, p := range l.Props {
+		names = append(names, p.XMLName)
+	}
+	return names
+}
+
+type propfindRequest struct {
+	XMLName xml.Name  `xml:"DAV: propfind"`
+	Prop    *propList `xml:"DAV: prop"`
+}
+
+// reportRequest is a calendar-query or a calendar-multiget.
+type reportRequest struct {
+	XMLName xml.Name
+	Prop    *propList `xml:"DAV: prop"`
+	Hrefs   []string  `xml:"DAV: href"`
+	Filter  *struct {
+		CompFilter struct {
+			CompFilters []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// propfind answers with the requested properties of the target and, at depth
+// 1, of its members. Infinite depth is served as depth 1.
+func (h *handler) propfind(w http.ResponseWriter, r *http.Request, t target) {
+	ctx := r.Context()
+
+	// an empty body asks for all properties
+	var names []xml.Name
+	req := propfindRequest{}
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req)
+	switch {
+	case errors.Is(err, io.EOF):
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case req.Prop != nil:
+		names = req.Prop.names()
+	}
+
+	members := r.Header.Get("Depth") != "0"
+
+	ms := newMultistatus()
+	switch t.kind {
+	case targetRoot:
+		ms.response(Root, rootProperties(t.ownerId), names)
+		if members {
+			ms.response(principalHref(t.ownerId), principalProperties(t.ownerId), names)
+		}
+	case targetPrincipal:
+		ms.response(principalHref(t.ownerId), principalProperties(t.ownerId), names)
+		if members {
+			tasks, err := h.repository.ExportTasksContext(ctx, t.ownerId)
+			if err != nil {
+				h.fail(w, err)
+				return
+			}
+			ms.response(collectionHref(t.ownerId), collectionProperties(t.ownerId, tasks), names)
+		}
+	case targetCollection:
+		tasks, err := h.repository.ExportTasksContext(ctx, t.ownerId)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+		ms.response(collectionHref(t.ownerId), collectionProperties(t.ownerId, tasks), names)
+		if members {
+			for _, task := range tasks {
+				props, err := resourceProperties(task, names)
+				if err != nil {
+					h.fail(w, err)
+					return
+				}
+				ms.response(resourceHref(task), props, names)
+			}
+		}
+	case targetResource:
+		task, err := h.task(ctx, t.ownerId, t.id)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+		props, err := resourceProperties(task, names)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+		ms.response(resourceHref(task), props, names)
+	}
+
+	ms.write(w)
+}
+
+// report answers a calendar-query with every task, or with none when it only
+// asks for other components than VTODO, and a calendar-multiget with the
+// tasks it names.
+func (h *handler) report(w http.ResponseWriter, r *http.Request, t target) {
+	ctx := r.Context()
+
+	req := reportRequest{}
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	names := []xml.Name{propGetETag}
+	if req.Prop != nil {
+		names = req.Prop.names()
+	}
+
+	ms := newMultistatus()
+	switch req.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		if req.Filter != nil && !asksForTodos(req.Filter.CompFilter.CompFilters) {
+			break
+		}
+
+		tasks, err := h.repository.ExportTasksContext(ctx, t.ownerId)
+		if err != nil {
+			h.fail(w, err)
+			return
+		}
+		for _, task := range tasks {
+			props, err := resourceProperties(task, names)
+			if err != nil {
+				h.fail(w, err)
+				return
+			}
+			ms.response(resourceHref(task), props, names)
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, memberHref := range req.Hrefs {
+			u, err := url.Parse(strings.TrimSpace(memberHref))
+			if err != nil {
+				ms.missing(memberHref, http.StatusNotFound)
+				continue
+			}
+			member, ok := parseTarget(u.Path)
+			if !ok || member.kind != targetResource || member.ownerId != t.ownerId {
+				ms.missing(memberHref, http.StatusNotFound)
+				continue
+			}
+
+			task, err := h.task(ctx, t.ownerId, member.id)
+			if errors.Is(err, sql.ErrNoRows) {
+				ms.missing(memberHref, http.StatusNotFound)
+				continue
+			}
+			if err != nil {
+				h.fail(w, err)
+				return
+			}
+			props, err := resourceProperties(task, names)
+			if err != nil {
+				h.fail(w, err)
+				return
+			}
+			ms.response(memberHref, props, names)
+		}
+	default:
+		http.Error(w, "unsupported report", http.StatusForbidden)
+		return
+	}
+
+	ms.write(w)
+}
+
+// asksForTodos reports whether the component filters of a VCALENDAR match
+// VTODO components, no filter matching all.
+func asksForTodos(filters []struct {
+	Name string `xml:"name,attr"`
+}) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if strings.EqualFold(f.Name, "VTODO") {
+			return true
+		}
+	}
+	return false
+}
+
+func rootProperties(ownerId int32) properties {
+	return properties{
+		{Space: nsDAV, Local: "resourcetype"}:           "<d:collection/>",
+		{Space: nsDAV, Local: "current-user-principal"}: href(principalHref(ownerId)),
+	}
+}
+
+func principalProperties(ownerId int32) properties {
+	return properties{
+		{Space: nsDAV, Local: "resourcetype"}:           "<d:collection/><d:principal/>",
+		{Space: nsDAV, Local: "displayname"}:            escapeText(fmt.Sprintf("Owner %d", ownerId)),
+		{Space: nsDAV, Local: "current-user-principal"}: href(principalHref(ownerId)),
+		{Space: nsDAV, Local: "principal-URL"}:          href(principalHref(ownerId)),
+		{Space: nsCalDAV, Local: "calendar-home-set"}:   href(principalHref(ownerId)),
+	}
+}
+
+// collectionProperties describe the calendar of the owner's tasks. The ctag
+// changes whenever a task is added, changed or deleted.
+func collectionProperties(ownerId int32, tasks []*model.Task) properties {
+	hash := sha256.New()
+	for _, task := range tasks {
+		_, _ = fmt.Fprintf(hash, "%s:%d\n", task.Id, task.Version)
+	}
+
+	return properties{
+		{Space: nsDAV, Local: "resourcetype"}:           "<d:collection/><c:calendar/>",
+		{Space: nsDAV, Local: "displayname"}:            "Tasks",
+		{Space: nsDAV, Local: "current-user-principal"}: href(principalHref(ownerId)),
+		{Space: nsDAV, Local: "owner"}:                  href(principalHref(ownerId)),
+		{Space: nsDAV, Local: "current-user-privilege-set"}: "<d:privilege><d:read/></d:privilege>" +
+			"<d:privilege><d:write/></d:privilege>" +
+			"<d:privilege><d:write-content/></d:privilege>" +
+			"<d:privilege><d:bind/></d:privilege>" +
+			"<d:privilege><d:unbind/></d:privilege>",
+		{Space: nsDAV, Local: "supported-report-set"}: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>",
+		{Space: nsCalDAV, Local: "supported-calendar-component-set"}: `<c:comp name="VTODO"/>`,
+		{Space: nsCalendarServer, Local: "getctag"}:                  hex.EncodeToString(hash.Sum(nil)[:16]),
+	}
+}
+
+// resourceProperties describe a task. Its calendar data is only rendered when
+// asked for.
+func resourceProperties(task *model.Task, names []xml.Name) (properties, error) {
+	props := properties{
+		{Space: nsDAV, Local: "resourcetype"}:   "",
+		{Space: nsDAV, Local: "getetag"}:        escapeText(etagOf(task)),
+		{Space: nsDAV, Local: "getcontenttype"}: calendarContentType + "; component=VTODO",
+	}
+
+	for _, name := range names {
+		if name != propCalendarData {
+			continue
+		}
+		body, err := encode(task)
+		if err != nil {
+			return nil, err
+		}
+		props[propCalendarData] = escapeText(string(body))
+	}
+
+	return props, nil
+}
+
+func href(path string) string {
+	return "<d:href>" + escapeText(path) + "</d:href>"
+}
+
+func escapeText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// multistatus builds a 207 Multi-Status response.
+type multistatus struct {
+	buf bytes.Buffer
+}
+
+func newMultistatus() *multistatus {
+	ms := &multistatus{}
+	ms.buf.WriteString(xml.Header)
+	ms.buf.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	return ms
+}
+
+// response lists the requested properties of a resource, found ones with 200
+// and unknown ones with 404. No names ask for all properties but the calendar
+// data.
+func (ms *multistatus) response(path string, props properties, names []xml.Name) {
+	ms.buf.WriteString("<d:response>")
+	ms.buf.WriteString(href(path))
+
+	found := make([]xml.Name, 0, len(props))
+	missing := make([]xml.Name, 0)
+	if names == nil {
+		for name := range props {
+			if name != propCalendarData {
+				found = append(found, name)
+			}
+		}
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].Space != found[j].Space {
+				return found[i].Space < found[j].Space
+			}
+			return found[i].Local < found[j].Local
+		})
+	} else {
+		for _, name := range names {
+			if _, ok := props[name]; ok {
+				found = append(found, name)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+	}
+
+	if len(found) != 0 {
+		ms.buf.WriteString("<d:propstat><d:prop>")
+		for _, name := range found {
+			ms.element(name, props[name])
+		}
+		ms.buf.WriteString("</d:prop>")
+		ms.status(http.StatusOK)
+		ms.buf.WriteString("</d:propstat>")
+	}
+	if len(missing) != 0 {
+		ms.buf.WriteString("<d:propstat><d:prop>")
+		for _, name := range missing {
+			ms.element(name, "")
+		}
+		ms.buf.WriteString("</d:prop>")
+		ms.status(http.StatusNotFound)
+		ms.buf.WriteString("</d:propstat>")
+	}
+
+	ms.buf.WriteString("</d:response>")
+}
+
+// missing reports a resource that cannot be listed.
+func (ms *multistatus) missing(path string, status int) {
+	ms.buf.WriteString("<d:response>")
+	ms.buf.WriteString(href(path))
+	ms.status(status)
+	ms.buf.WriteString("</d:response>")
+}
+
+func (ms *multistatus) element(name xml.Name, content string) {
+	tag := name.Local
+	attrs := ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		attrs = ` xmlns="` + escapeText(name.Space) + `"`
+	}
+
+	if content == "" {
+		ms.buf.WriteString("<" + tag + attrs + "/>")
+		return
+	}
+	ms.buf.WriteString("<" + tag + attrs + ">" + content + "</" + tag + ">")
+}
+
+func (ms *multistatus) status(status int) {
+	ms.buf.WriteString("<d:status>HTTP/1.1 " + strconv.Itoa(status) + " " + http.StatusText(status) + "</d:status>")
+}
+
+func (ms *multistatus) write(w http.ResponseWriter) {
+	ms.buf.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write(ms.buf.Bytes())
+}
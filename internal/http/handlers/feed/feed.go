@@ -0,0 +1,84 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	repository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/exchange/ical"
+	slogattr "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/lib/log/slog/attr"
+)
+
+type FeedProvider interface {
+	FeedContext(ctx context.Context, token string) (*model.FeedToken, []*model.Task, error)
+}
+
+// MakeFeedHandler serves the ICS feed of the token in the "token" path value,
+// with or without an .ics extension. The DTSTAMP of the feed is fixed to the
+// creation of the token, so the ETag only changes with the tasks.
+func MakeFeedHandler(log *slog.Logger, provider FeedProvider) http.HandlerFunc {
+	const op = "http.handlers.feed.MakeFeedHandler"
+
+	log = log.With(
+		slog.String("op", op),
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+		feedToken, tasks, err := provider.FeedContext(r.Context(), token)
+		if errors.Is(err, repository.ErrFeedTokenNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Error("error getting feed", slogattr.Err(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		var body bytes.Buffer
+		err = ical.Encode(&body, tasks, feedToken.CreatedAt)
+		if err != nil {
+			log.Error("error encoding feed", slogattr.Err(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+
+		if matchesETag(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		_, _ = w.Write(body.Bytes())
+	}
+}
+
+// matchesETag reports whether an If-None-Match header matches etag, comparing
+// weakly as RFC 9110 requires for If-None-Match.
+func matchesETag(header string, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+
+	repository "github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/database/repository/postgres"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/http/handlers/caldav"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/http/handlers/feed"
+)
+
+func NewHandler(log *slog.Logger, r *repository.Repository) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /feeds/{token}", feed.MakeFeedHandler(log, r))
+
+	mux.Handle(caldav.Root, caldav.MakeCalDAVHandler(log, r))
+	mux.Handle("/.well-known/caldav", http.RedirectHandler(caldav.Root, http.StatusMovedPermanently))
+
+	return mux
+}
@@ -0,0 +1,229 @@
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits are ordered by their byte values, so keys compare correctly with a
+// plain byte-wise comparison (COLLATE "C" in PostgreSQL).
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// smallestInteger is the integer part no key may be placed before.
+const smallestInteger = "A" + "00000000000000000000000000"
+
+var (
+	ErrInvalidKey    = errors.New("invalid rank key")
+	ErrInvalidBounds = errors.New("invalid rank bounds")
+	ErrExhausted     = errors.New("rank keys exhausted")
+)
+
+// Between returns a key that sorts strictly between a and b. An empty a means
+// "before everything" and an empty b means "after everything", so
+// Between("", "") returns the first key of an empty list.
+//
+// Keys follow the fractional indexing scheme by David Greenspan: a variable
+// length integer part, whose first character encodes its length, followed by
+// an optional fraction. Appending to or prepending to a list only changes the
+// integer part, so keys stay short for the common cases.
+func Between(a string, b string) (string, error) {
+	if a != "" && !valid(a) {
+		return "", ErrInvalidKey
+	}
+	if b != "" && !valid(b) {
+		return "", ErrInvalidKey
+	}
+	if a != "" && b != "" && a >= b {
+		return "", ErrInvalidBounds
+	}
+
+	if a == "" {
+		if b == "" {
+			return "a" + string(digits[0]), nil
+		}
+
+		ib := integerPart(b)
+		fb := b[len(ib):]
+		if ib == smallestInteger {
+			return ib + midpoint("", fb), nil
+		}
+		if ib < b {
+			return ib, nil
+		}
+		i, ok := decrementInteger(ib)
+		if !ok {
+			return "", ErrExhausted
+		}
+		if i == smallestInteger {
+			// the smallest integer is only valid with a fraction
+			return i + midpoint("", ""), nil
+		}
+		return i, nil
+	}
+
+	ia := integerPart(a)
+	fa := a[len(ia):]
+
+	if b == "" {
+		i, ok := incrementInteger(ia)
+		if !ok {
+			return ia + midpoint(fa, ""), nil
+		}
+		return i, nil
+	}
+
+	ib := integerPart(b)
+	fb := b[len(ib):]
+	if ia == ib {
+		return ia + midpoint(fa, fb), nil
+	}
+
+	i, ok := incrementInteger(ia)
+	if !ok {
+		return "", ErrExhausted
+	}
+	if i < b {
+		return i, nil
+	}
+	return ia + midpoint(fa, ""), nil
+}
+
+// midpoint returns a fraction strictly between a and b, where an empty b means
+// "after everything". Fractions never end with the smallest digit.
+func midpoint(a string, b string) string {
+	if b != "" {
+		// keep the common prefix, treating missing digits of a as zeros
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+
+	digitB := len(digits)
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(digits[(digitA+digitB+1)/2])
+	}
+
+	// the first digits are consecutive
+	if len(b) > 1 {
+		return b[:1]
+	}
+
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[digitA]) + midpoint(rest, "")
+}
+
+// integerLength returns the length of the integer part starting with head:
+// "a".."z" start positive integers of 2..27 characters and "Z".."A" negative
+// ones of the same lengths.
+func integerLength(head byte) int {
+	switch {
+	case head >= 'a' && head <= 'z':
+		return int(head-'a') + 2
+	case head >= 'A' && head <= 'Z':
+		return int('Z'-head) + 2
+	default:
+		return 0
+	}
+}
+
+func integerPart(key string) string {
+	return key[:integerLength(key[0])]
+}
+
+func incrementInteger(x string) (string, bool) {
+	head := x[0]
+	digs := []byte(x[1:])
+
+	for i := len(digs) - 1; i >= 0; i-- {
+		d := strings.IndexByte(digits, digs[i]) + 1
+		if d < len(digits) {
+			digs[i] = digits[d]
+			return string(head) + string(digs), true
+		}
+		digs[i] = digits[0]
+	}
+
+	switch head {
+	case 'Z':
+		return "a" + string(digits[0]), true
+	case 'z':
+		return "", false
+	}
+
+	head++
+	if head > 'a' {
+		digs = append(digs, digits[0])
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
+
+func decrementInteger(x string) (string, bool) {
+	head := x[0]
+	digs := []byte(x[1:])
+
+	for i := len(digs) - 1; i >= 0; i-- {
+		d := strings.IndexByte(digits, digs[i]) - 1
+		if d >= 0 {
+			digs[i] = digits[d]
+			return string(head) + string(digs), true
+		}
+		digs[i] = digits[len(digits)-1]
+	}
+
+	switch head {
+	case 'a':
+		return "Z" + string(digits[len(digits)-1]), true
+	case 'A':
+		return "", false
+	}
+
+	head--
+	if head < 'Z' {
+		digs = append(digs, digits[len(digits)-1])
+	} else {
+		digs = digs[:len(digs)-1]
+	}
+	return string(head) + string(digs), true
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+func valid(key string) bool {
+	n := integerLength(key[0])
+	if n == 0 || len(key) < n || key == smallestInteger {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return len(key) == n || key[len(key)-1] != digits[0]
+}
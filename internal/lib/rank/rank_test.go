@@ -0,0 +1,133 @@
+package rank
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	smallest := smallestInteger
+	largest := "z" + strings.Repeat("z", 26)
+
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		want    string
+		wantErr error
+	}{
+		{name: "empty list", a: "", b: "", want: "a0"},
+		{name: "append", a: "a0", b: "", want: "a1"},
+		{name: "append grows the integer", a: "az", b: "", want: "b00"},
+		{name: "prepend", a: "", b: "a0", want: "Zz"},
+		{name: "prepend grows a negative integer", a: "", b: "Y00", want: "Xzzz"},
+		{name: "prepend before a fraction", a: "", b: "a0V", want: "a0"},
+		{name: "between integers", a: "a0", b: "a2", want: "a1"},
+		{name: "between consecutive integers", a: "a0", b: "a1", want: "a0V"},
+		{name: "between fractions", a: "a0", b: "a0V", want: "a0G"},
+		{name: "between consecutive digits", a: "a0V", b: "a0W", want: "a0VV"},
+		{name: "prepend before the smallest integer", a: "", b: smallest + "V", want: smallest + "G"},
+		{name: "prepend next to the smallest integer", a: "", b: "A" + strings.Repeat("0", 25) + "1", want: smallest + "V"},
+		{name: "append after the largest integer", a: largest, b: "", want: largest + "V"},
+		{name: "invalid a", a: "!", b: "", wantErr: ErrInvalidKey},
+		{name: "invalid b", a: "", b: "a", wantErr: ErrInvalidKey},
+		{name: "trailing zero", a: "a00", b: "", wantErr: ErrInvalidKey},
+		{name: "smallest integer", a: "", b: smallest, wantErr: ErrInvalidKey},
+		{name: "equal bounds", a: "a1", b: "a1", wantErr: ErrInvalidBounds},
+		{name: "reversed bounds", a: "a2", b: "a1", wantErr: ErrInvalidBounds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.a, tt.b)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Between(%q, %q) error = %v, want %v", tt.a, tt.b, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Between(%q, %q) error = %v", tt.a, tt.b, err)
+			}
+			if got != tt.want {
+				t.Errorf("Between(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+			checkBetween(t, tt.a, got, tt.b)
+		})
+	}
+}
+
+func TestBetweenRepeated(t *testing.T) {
+	tests := []struct {
+		name string
+		next func(keys []string) (string, string, int)
+	}{
+		{
+			name: "append",
+			next: func(keys []string) (string, string, int) {
+				return keys[len(keys)-1], "", len(keys)
+			},
+		},
+		{
+			name: "prepend",
+			next: func(keys []string) (string, string, int) {
+				return "", keys[0], 0
+			},
+		},
+		{
+			name: "insert after the first",
+			next: func(keys []string) (string, string, int) {
+				if len(keys) == 1 {
+					return keys[0], "", 1
+				}
+				return keys[0], keys[1], 1
+			},
+		},
+		{
+			name: "insert before the last",
+			next: func(keys []string) (string, string, int) {
+				if len(keys) == 1 {
+					return "", keys[0], 0
+				}
+				return keys[len(keys)-2], keys[len(keys)-1], len(keys) - 1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := []string{"a0"}
+			for i := 0; i < 1000; i++ {
+				a, b, at := tt.next(keys)
+				key, err := Between(a, b)
+				if err != nil {
+					t.Fatalf("Between(%q, %q) error = %v after %d keys", a, b, err, len(keys))
+				}
+				checkBetween(t, a, key, b)
+
+				keys = append(keys[:at], append([]string{key}, keys[at:]...)...)
+			}
+
+			for i := 1; i < len(keys); i++ {
+				if keys[i-1] >= keys[i] {
+					t.Fatalf("keys out of order at %d: %q >= %q", i, keys[i-1], keys[i])
+				}
+			}
+		})
+	}
+}
+
+func checkBetween(t *testing.T, a string, key string, b string) {
+	t.Helper()
+
+	if !valid(key) {
+		t.Errorf("key %q is not valid", key)
+	}
+	if a != "" && key <= a {
+		t.Errorf("key %q does not sort after %q", key, a)
+	}
+	if b != "" && key >= b {
+		t.Errorf("key %q does not sort before %q", key, b)
+	}
+}
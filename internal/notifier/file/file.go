@@ -0,0 +1,69 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+// Notifier appends notifications to a file as JSON lines. It is meant for local
+// testing.
+type Notifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+type notification struct {
+	Event    string    `json:"event"`
+	TaskId   string    `json:"task_id"`
+	Header   string    `json:"header"`
+	OwnerId  int32     `json:"owner_id"`
+	Deadline time.Time `json:"deadline"`
+}
+
+func NewNotifier(path string) *Notifier {
+	return &Notifier{path: path}
+}
+
+func (n *Notifier) NotifyOverdue(_ context.Context, task *model.Task) error {
+	const op = "notifier.file.NotifyOverdue"
+
+	err := n.write(notification{
+		Event:    "overdue",
+		TaskId:   task.Id.String(),
+		Header:   task.Header,
+		OwnerId:  task.OwnerId,
+		Deadline: task.Deadline,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (n *Notifier) write(v notification) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,13 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+// Notifier delivers events about a task to its owner.
+type Notifier interface {
+	// NotifyOverdue reports that the deadline of the task has passed.
+	NotifyOverdue(ctx context.Context, task *model.Task) error
+}
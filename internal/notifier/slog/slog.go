@@ -0,0 +1,28 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+)
+
+// Notifier writes notifications to the log. It is meant for local testing.
+type Notifier struct {
+	log *slog.Logger
+}
+
+func NewNotifier(log *slog.Logger) *Notifier {
+	return &Notifier{log: log}
+}
+
+func (n *Notifier) NotifyOverdue(ctx context.Context, task *model.Task) error {
+	n.log.InfoContext(ctx, "task overdue",
+		slog.String("task_id", task.Id.String()),
+		slog.String("header", task.Header),
+		slog.Int("owner_id", int(task.OwnerId)),
+		slog.Time("deadline", task.Deadline),
+	)
+
+	return nil
+}
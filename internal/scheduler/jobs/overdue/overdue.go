@@ -0,0 +1,70 @@
+package overdue
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/domain/model"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/notifier"
+	"github.com/g-vinokurov/pyramidum-backend-service-tasks/internal/scheduler"
+)
+
+type OverdueTaskProvider interface {
+	NotifyOverdueTasksContext(
+		ctx context.Context,
+		now time.Time,
+		limit uint64,
+		notify func(context.Context, *model.Task) error,
+	) (int, error)
+	EscalateUrgentTasksContext(ctx context.Context, now time.Time, window time.Duration) (int64, error)
+}
+
+// MakeOverdueJob reports overdue tasks in batches of batchSize, each given at
+// most batchTimeout, and, when urgentWindow is positive, marks tasks whose
+// deadline is closer than urgentWindow as urgent.
+func MakeOverdueJob(
+	log *slog.Logger,
+	provider OverdueTaskProvider,
+	n notifier.Notifier,
+	batchSize uint64,
+	batchTimeout time.Duration,
+	urgentWindow time.Duration,
+) scheduler.JobFunc {
+	const op = "scheduler.jobs.overdue.MakeOverdueJob"
+
+	log = log.With(
+		slog.String("op", op),
+	)
+
+	return func(ctx context.Context) error {
+		now := time.Now().UTC()
+
+		if urgentWindow > 0 {
+			escalated, err := provider.EscalateUrgentTasksContext(ctx, now, urgentWindow)
+			if err != nil {
+				return err
+			}
+			if escalated != 0 {
+				log.Info("tasks escalated to urgent", slog.Int64("count", escalated))
+			}
+		}
+
+		for {
+			// the batch keeps its tasks locked while the notifier runs
+			batchCtx, cancel := context.WithTimeout(ctx, batchTimeout)
+			notified, err := provider.NotifyOverdueTasksContext(batchCtx, now, batchSize, n.NotifyOverdue)
+			cancel()
+			if notified != 0 {
+				log.Info("overdue tasks reported", slog.Int("count", notified))
+			}
+			if err != nil {
+				return err
+			}
+
+			if uint64(notified) < batchSize {
+				return nil
+			}
+		}
+	}
+}
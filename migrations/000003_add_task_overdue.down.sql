@@ -0,0 +1,4 @@
+DROP INDEX task_open_deadline_idx;
+
+ALTER TABLE task
+    DROP COLUMN overdue_notified_at;
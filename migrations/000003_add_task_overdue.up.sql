@@ -0,0 +1,4 @@
+ALTER TABLE task
+    ADD COLUMN overdue_notified_at TIMESTAMP;
+
+CREATE INDEX task_open_deadline_idx ON task (deadline) WHERE progress_status = 'in progress';
@@ -0,0 +1,21 @@
+DROP INDEX task_open_deadline_idx;
+
+ALTER TABLE task
+    DROP COLUMN canceled_at,
+    DROP COLUMN completed_at;
+
+UPDATE task SET progress_status = 'in progress' WHERE progress_status IN ('backlog', 'blocked', 'on hold');
+
+ALTER TYPE progress_status RENAME TO progress_status_old;
+
+CREATE TYPE progress_status AS ENUM(
+    'canceled',
+    'in progress',
+    'done'
+);
+
+ALTER TABLE task ALTER COLUMN progress_status TYPE progress_status USING progress_status::TEXT::progress_status;
+
+DROP TYPE progress_status_old;
+
+CREATE INDEX task_open_deadline_idx ON task (deadline) WHERE progress_status = 'in progress';
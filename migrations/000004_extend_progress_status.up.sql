@@ -0,0 +1,11 @@
+ALTER TYPE progress_status ADD VALUE IF NOT EXISTS 'backlog';
+ALTER TYPE progress_status ADD VALUE IF NOT EXISTS 'blocked';
+ALTER TYPE progress_status ADD VALUE IF NOT EXISTS 'on hold';
+
+ALTER TABLE task
+    ADD COLUMN completed_at TIMESTAMP,
+    ADD COLUMN canceled_at TIMESTAMP;
+
+DROP INDEX task_open_deadline_idx;
+
+CREATE INDEX task_open_deadline_idx ON task (deadline) WHERE progress_status NOT IN ('done', 'canceled');
@@ -0,0 +1,4 @@
+DROP INDEX task_siblings_rank_idx;
+
+ALTER TABLE task
+    DROP COLUMN rank;
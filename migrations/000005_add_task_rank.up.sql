@@ -0,0 +1,17 @@
+ALTER TABLE task
+    ADD COLUMN rank TEXT COLLATE "C";
+
+-- existing siblings keep their creation order; "h" starts an 8-digit integer
+-- part of the fractional index
+UPDATE task
+SET rank = ranked.rank
+FROM (
+    SELECT id, 'h' || LPAD((ROW_NUMBER() OVER (PARTITION BY owner_id, parent_id ORDER BY created_at, id))::TEXT, 8, '0') AS rank
+    FROM task
+) AS ranked
+WHERE task.id = ranked.id;
+
+ALTER TABLE task
+    ALTER COLUMN rank SET NOT NULL;
+
+CREATE INDEX task_siblings_rank_idx ON task (owner_id, parent_id, rank);
@@ -0,0 +1,3 @@
+DROP TABLE task_tag;
+
+DROP TABLE tag;
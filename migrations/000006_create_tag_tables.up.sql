@@ -0,0 +1,19 @@
+CREATE TABLE tag (
+    id UUID UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    owner_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (owner_id, name)
+);
+
+CREATE TABLE task_tag (
+    task_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    PRIMARY KEY (task_id, tag_id),
+    FOREIGN KEY (task_id) REFERENCES task (id),
+    FOREIGN KEY (tag_id) REFERENCES tag (id) ON DELETE CASCADE
+);
+
+CREATE INDEX task_tag_tag_idx ON task_tag (tag_id);
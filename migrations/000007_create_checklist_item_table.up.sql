@@ -0,0 +1,12 @@
+CREATE TABLE checklist_item (
+    id UUID UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    task_id UUID NOT NULL,
+    text TEXT NOT NULL,
+    is_checked BOOLEAN NOT NULL DEFAULT FALSE,
+    rank TEXT COLLATE "C" NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (task_id) REFERENCES task (id) ON DELETE CASCADE
+);
+
+CREATE INDEX checklist_item_task_rank_idx ON checklist_item (task_id, rank);
@@ -0,0 +1,2 @@
+ALTER TABLE task
+    DROP COLUMN deleted_at;
@@ -0,0 +1,11 @@
+-- only the SHA-256 of a token is stored, the token itself is shown once
+CREATE TABLE feed_token (
+    id UUID UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    owner_id INTEGER NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    revoked_at TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX feed_token_owner_idx ON feed_token (owner_id);
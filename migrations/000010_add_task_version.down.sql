@@ -0,0 +1,9 @@
+DROP TRIGGER checklist_item_task_version ON checklist_item;
+DROP TRIGGER task_tag_task_version ON task_tag;
+DROP TRIGGER external_image_task_version ON external_image;
+DROP FUNCTION touch_task();
+
+DROP TRIGGER task_version ON task;
+DROP FUNCTION bump_task_version();
+
+ALTER TABLE task DROP COLUMN version;
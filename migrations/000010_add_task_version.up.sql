@@ -0,0 +1,42 @@
+-- version changes whenever the task or what belongs to it changes, so that
+-- clients can tell whether their copy is current, and modified_at with it
+ALTER TABLE task ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE FUNCTION bump_task_version() RETURNS trigger AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    NEW.modified_at := NOW() AT TIME ZONE 'UTC';
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_version
+    BEFORE UPDATE ON task
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION bump_task_version();
+
+CREATE FUNCTION touch_task() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE task SET version = version + 1 WHERE id = NEW.task_id;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE task SET version = version + 1 WHERE id = OLD.task_id;
+    ELSE
+        UPDATE task SET version = version + 1 WHERE id IN (OLD.task_id, NEW.task_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER external_image_task_version
+    AFTER INSERT OR UPDATE OR DELETE ON external_image
+    FOR EACH ROW EXECUTE FUNCTION touch_task();
+
+CREATE TRIGGER task_tag_task_version
+    AFTER INSERT OR UPDATE OR DELETE ON task_tag
+    FOR EACH ROW EXECUTE FUNCTION touch_task();
+
+CREATE TRIGGER checklist_item_task_version
+    AFTER INSERT OR UPDATE OR DELETE ON checklist_item
+    FOR EACH ROW EXECUTE FUNCTION touch_task();
@@ -0,0 +1,3 @@
+ALTER TABLE feed_token DROP COLUMN scope;
+
+DROP TYPE token_scope;
@@ -0,0 +1,4 @@
+-- feed tokens only read the ICS feed, caldav tokens read and write tasks
+CREATE TYPE token_scope AS ENUM ('feed', 'caldav');
+
+ALTER TABLE feed_token ADD COLUMN scope token_scope NOT NULL DEFAULT 'feed';
@@ -0,0 +1,7 @@
+DROP TRIGGER task_version ON task;
+
+CREATE TRIGGER task_version
+    BEFORE UPDATE ON task
+    FOR EACH ROW
+    WHEN (OLD.* IS DISTINCT FROM NEW.*)
+    EXECUTE FUNCTION bump_task_version();
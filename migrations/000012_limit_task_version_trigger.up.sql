@@ -0,0 +1,13 @@
+-- overdue notifications are bookkeeping, they should not make clients
+-- refetch a task
+DROP TRIGGER task_version ON task;
+
+CREATE TRIGGER task_version
+    BEFORE UPDATE ON task
+    FOR EACH ROW
+    WHEN (
+        to_jsonb(OLD) - 'overdue_notified_at'
+        IS DISTINCT FROM
+        to_jsonb(NEW) - 'overdue_notified_at'
+    )
+    EXECUTE FUNCTION bump_task_version();
@@ -22,6 +22,12 @@ GRPC_PORT=${GRPC_PORT:-6969}
 read -p "Введите таймаут для gRPC (по умолчанию 5s): " GRPC_TIMEOUT
 GRPC_TIMEOUT=${GRPC_TIMEOUT:-5s}
 
+read -p "Введите порт для HTTP (по умолчанию 8080): " HTTP_PORT
+HTTP_PORT=${HTTP_PORT:-8080}
+
+read -p "Введите таймаут для HTTP (по умолчанию 10s): " HTTP_TIMEOUT
+HTTP_TIMEOUT=${HTTP_TIMEOUT:-10s}
+
 read -p "Введите хост для PostgreSQL (по умолчанию localhost): " POSTGRES_HOST
 POSTGRES_HOST=${POSTGRES_HOST:-localhost}
 
@@ -43,12 +49,36 @@ POSTGRES_SSLMODE=${POSTGRES_SSLMODE:-disable}
 read -p "Введите путь к миграциям (по умолчанию ./migrations): " MIGRATIONS_PATH
 MIGRATIONS_PATH=${MIGRATIONS_PATH:-./migrations}
 
+read -p "Введите интервал проверки напоминаний (по умолчанию 30s): " SCHEDULER_REMINDERS_INTERVAL
+SCHEDULER_REMINDERS_INTERVAL=${SCHEDULER_REMINDERS_INTERVAL:-30s}
+
+read -p "Введите интервал проверки просроченных задач (по умолчанию 1m): " SCHEDULER_OVERDUE_INTERVAL
+SCHEDULER_OVERDUE_INTERVAL=${SCHEDULER_OVERDUE_INTERVAL:-1m}
+
+read -p "Введите окно автоматической срочности, 0s отключает (по умолчанию 0s): " SCHEDULER_URGENT_WINDOW
+SCHEDULER_URGENT_WINDOW=${SCHEDULER_URGENT_WINDOW:-0s}
+
+read -p "Введите размер пакета для фоновых задач (по умолчанию 100): " SCHEDULER_BATCH_SIZE
+SCHEDULER_BATCH_SIZE=${SCHEDULER_BATCH_SIZE:-100}
+
+read -p "Введите тип уведомлений, log или file (по умолчанию log): " NOTIFIER_TYPE
+NOTIFIER_TYPE=${NOTIFIER_TYPE:-log}
+
+read -p "Введите путь к файлу уведомлений (по умолчанию ./reminders.jsonl): " NOTIFIER_FILE_PATH
+NOTIFIER_FILE_PATH=${NOTIFIER_FILE_PATH:-./reminders.jsonl}
+
+read -p "Введите правила переходов статусов (по умолчанию стандартные): " WORKFLOW_TRANSITIONS
+
 # Заполнение файла .env
 cat <<EOL > $ENV_FILE
 # gRPC Configuration
 GRPC_PORT=$GRPC_PORT
 GRPC_TIMEOUT=$GRPC_TIMEOUT
 
+# HTTP Configuration (calendar feeds)
+HTTP_PORT=$HTTP_PORT
+HTTP_TIMEOUT=$HTTP_TIMEOUT
+
 # PostgreSQL Configuration
 POSTGRES_HOST=$POSTGRES_HOST
 POSTGRES_PORT=$POSTGRES_PORT
@@ -59,6 +89,19 @@ POSTGRES_SSLMODE=$POSTGRES_SSLMODE
 
 # Migrations Configuration
 MIGRATIONS_PATH=$MIGRATIONS_PATH
+
+# Scheduler Configuration
+SCHEDULER_REMINDERS_INTERVAL=$SCHEDULER_REMINDERS_INTERVAL
+SCHEDULER_OVERDUE_INTERVAL=$SCHEDULER_OVERDUE_INTERVAL
+SCHEDULER_URGENT_WINDOW=$SCHEDULER_URGENT_WINDOW
+SCHEDULER_BATCH_SIZE=$SCHEDULER_BATCH_SIZE
+
+# Notifier Configuration (log or file)
+NOTIFIER_TYPE=$NOTIFIER_TYPE
+NOTIFIER_FILE_PATH=$NOTIFIER_FILE_PATH
+
+# Workflow Configuration (empty for the default transitions)
+WORKFLOW_TRANSITIONS=$WORKFLOW_TRANSITIONS
 EOL
 
 echo "$ENV_FILE успешно создан и заполнен."